@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+
+	"os"
+
+	"github.com/ovotech/gitoops/pkg/buildkite"
+	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
+)
+
+func cmdBuildkite(cmd *flag.FlagSet) {
+	// Setup common params and parse command.
+	setupCommonFlags()
+	cmd.Parse(os.Args[2:])
+	validateCommonParams()
+	initLogging()
+	validateBuildkiteParams()
+
+	log.Infof("Running Buildkite ingestors")
+
+	if *buildkiteOrganization == "" {
+		*buildkiteOrganization = organization
+	}
+
+	db := database.GetDB(neo4jURI, neo4jUser, neo4jPassword)
+
+	bk := buildkite.GetBuildkite(
+		db,
+		*buildkiteGraphQLURL,
+		*buildkiteRESTURL,
+		organization,
+		*buildkiteOrganization,
+		*buildkiteToken,
+		session,
+	)
+	bk.Sync()
+}
+
+func validateBuildkiteParams() {
+	if *buildkiteToken == "" {
+		log.Fatalf("The -token flag is required. See help for more details.")
+	}
+}
@@ -23,18 +23,19 @@ func cmdCircleCI(cmd *flag.FlagSet) {
 
 	db := database.GetDB(neo4jURI, neo4jUser, neo4jPassword)
 
-	cci := circleci.GetCircleCI(db, organization, *circleCICookie, session)
+	cci := circleci.GetCircleCI(
+		db,
+		*circleCIURL,
+		organization,
+		*circleCICookie,
+		*circleCIToken,
+		session,
+	)
 	cci.Sync()
 }
 
 func validateCircleCIParams() {
-	requiredFlags := map[string]string{
-		*circleCICookie: "-cookie",
-	}
-
-	for k, v := range requiredFlags {
-		if k == "" {
-			log.Fatalf("The %s flag is required. See help for more details.", v)
-		}
+	if *circleCICookie == "" && *circleCIToken == "" {
+		log.Fatalf("One of the -cookie or -token flags is required. See help for more details.")
 	}
 }
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+
+	"os"
+
+	"github.com/ovotech/gitoops/pkg/cloudci"
+	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
+)
+
+func cmdCloudCI(cmd *flag.FlagSet) {
+	// Setup common params and parse command.
+	setupCommonFlags()
+	cmd.Parse(os.Args[2:])
+	validateCommonParams()
+	initLogging()
+	validateCloudCIParams()
+
+	log.Infof("Running cloud CI/CD ingestors")
+
+	db := database.GetDB(neo4jURI, neo4jUser, neo4jPassword)
+
+	cc := cloudci.GetCloudCI(
+		db,
+		organization,
+		*cloudCICodeBuildProjects,
+		*cloudCICloudBuildTriggers,
+		session,
+	)
+	cc.Sync()
+}
+
+func validateCloudCIParams() {
+	if *cloudCICodeBuildProjects == "" && *cloudCICloudBuildTriggers == "" {
+		log.Fatalf("One of the -codebuild-projects or -cloudbuild-triggers flags is required. See help for more details.")
+	}
+}
@@ -20,7 +20,9 @@ func cmdGitHub(cmd *flag.FlagSet) {
 		"ingestor",
 		"Ingestors to call. Supports: Organizations, Teams, Users, Repos, TeamRepos, "+
 			"TeamMembers, Default (all previous), RepoWebhooks, OrganizationSecrets, "+
-			"Environments, EnvironmentSecrets, Secrets (all GitHub secrets-related ingestors). "+
+			"Environments, EnvironmentSecrets, Secrets (all GitHub secrets-related ingestors), "+
+			"BranchFiles (CI/CD files on protected and environment deployment branches), "+
+			"OrganizationOIDC, RepoOIDC, OIDC (all OIDC subject claim ingestors). "+
 			"May be used multiple times.",
 	)
 	// Parse arguments
@@ -37,11 +39,28 @@ func cmdGitHub(cmd *flag.FlagSet) {
 		log.Fatalf("Error parsing topics: %s", err)
 	}
 
+	// Resolve the CI/CD configuration files to ingest from repositories
+	filePatterns := github.DefaultFilePatterns
+	if *githubFilesConfig != "" {
+		filePatterns, err = github.LoadFilePatterns(*githubFilesConfig)
+		if err != nil {
+			log.Fatalf("Error loading files config: %s", err)
+		}
+	}
+
 	// Set up DB
 	db := database.GetDB(neo4jURI, neo4jUser, neo4jPassword)
 
 	// Now we can actually call the ingestor
-	gh := github.GetGitHub(db, *githubRESTURL, *githubGraphQLURL, *githubToken, organization, session)
+	gh := github.GetGitHub(
+		db,
+		*githubRESTURL,
+		*githubGraphQLURL,
+		*githubToken,
+		organization,
+		session,
+		filePatterns,
+	)
 	gh.SyncByIngestorNames(ingestorNames)
 }
 
@@ -73,6 +92,9 @@ func resolveIngestorNames(names []string) ([]string, error) {
 		"environments",
 		"environmentsecrets",
 		"reposecrets",
+		"branchfiles",
+		"organizationoidc",
+		"repooidc",
 	}
 	defaultNames := []string{
 		"organizations",
@@ -88,6 +110,10 @@ func resolveIngestorNames(names []string) ([]string, error) {
 		"environmentsecrets",
 		"reposecrets",
 	}
+	oidcNames := []string{
+		"organizationoidc",
+		"repooidc",
+	}
 
 	// If no names were passed on CLI, we return default names
 	if len(names) == 0 {
@@ -106,6 +132,12 @@ func resolveIngestorNames(names []string) ([]string, error) {
 		names = append(names, secretsNames...)
 	}
 
+	// Expand OIDC names
+	if sliceContains(names, "oidc") {
+		names = sliceRemove(names, "oidc")
+		names = append(names, oidcNames...)
+	}
+
 	// Validate all names
 	for _, name := range names {
 		if !sliceContains(validNames, name) {
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+
+	"os"
+
+	"github.com/ovotech/gitoops/pkg/database"
+	"github.com/ovotech/gitoops/pkg/jenkins"
+	log "github.com/sirupsen/logrus"
+)
+
+func cmdJenkins(cmd *flag.FlagSet) {
+	// Setup common params and parse command.
+	setupCommonFlags()
+	cmd.Parse(os.Args[2:])
+	validateCommonParams()
+	initLogging()
+	validateJenkinsParams()
+
+	log.Infof("Running Jenkins ingestors")
+
+	db := database.GetDB(neo4jURI, neo4jUser, neo4jPassword)
+
+	jk := jenkins.GetJenkins(
+		db,
+		*jenkinsURL,
+		organization,
+		*jenkinsUsername,
+		*jenkinsToken,
+		session,
+	)
+	jk.Sync()
+}
+
+func validateJenkinsParams() {
+	if *jenkinsURL == "" || *jenkinsUsername == "" || *jenkinsToken == "" {
+		log.Fatalf("The -jenkins-url, -username and -token flags are required. See help for more details.")
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+
+	"os"
+
+	"github.com/ovotech/gitoops/pkg/database"
+	"github.com/ovotech/gitoops/pkg/travis"
+	log "github.com/sirupsen/logrus"
+)
+
+func cmdTravis(cmd *flag.FlagSet) {
+	// Setup common params and parse command.
+	setupCommonFlags()
+	cmd.Parse(os.Args[2:])
+	validateCommonParams()
+	initLogging()
+	validateTravisParams()
+
+	log.Infof("Running Travis CI ingestors")
+
+	db := database.GetDB(neo4jURI, neo4jUser, neo4jPassword)
+
+	tr := travis.GetTravis(
+		db,
+		*travisURL,
+		organization,
+		*travisToken,
+		session,
+	)
+	tr.Sync()
+}
+
+func validateTravisParams() {
+	if *travisToken == "" {
+		log.Fatalf("The -token flag is required. See help for more details.")
+	}
+}
@@ -20,11 +20,16 @@ var (
 	neo4jPassword string
 	session       string
 
-	githubCmd        = flag.NewFlagSet("github", flag.ExitOnError)
-	githubToken      = githubCmd.String("token", "", "The GitHub access token.")
-	githubRESTURL    = githubCmd.String("github-rest-url", "https://api.github.com", "The target GitHub API URL.")
-	githubGraphQLURL = githubCmd.String("github-graphql-url", "https://api.github.com/graphql", "The target GitHub GraphQL URL.")
-	githubIngestors  arrayFlags
+	githubCmd         = flag.NewFlagSet("github", flag.ExitOnError)
+	githubToken       = githubCmd.String("token", "", "The GitHub access token.")
+	githubRESTURL     = githubCmd.String("github-rest-url", "https://api.github.com", "The target GitHub API URL.")
+	githubGraphQLURL  = githubCmd.String("github-graphql-url", "https://api.github.com/graphql", "The target GitHub GraphQL URL.")
+	githubIngestors   arrayFlags
+	githubFilesConfig = githubCmd.String(
+		"files-config",
+		"",
+		"Path to a YAML or JSON file with a 'files' list of additional file paths or globs to ingest from repositories.",
+	)
 
 	circleCICmd    = flag.NewFlagSet("circleci", flag.ExitOnError)
 	circleCICookie = circleCICmd.String(
@@ -32,13 +37,64 @@ var (
 		"",
 		"The 'ring-session' cookie from a CircleCI browser session. Get this from the network tab as you're browsing the CircleCI app authenticated.",
 	)
+	circleCIToken = circleCICmd.String(
+		"token",
+		"",
+		"A CircleCI personal API token, which may be used instead of -cookie.",
+	)
+	circleCIURL = circleCICmd.String("circleci-url", "https://circleci.com", "The target CircleCI URL.")
+
+	travisCmd   = flag.NewFlagSet("travis", flag.ExitOnError)
+	travisToken = travisCmd.String("token", "", "A Travis CI API token.")
+	travisURL   = travisCmd.String("travis-url", "https://api.travis-ci.com", "The target Travis CI API URL.")
+
+	jenkinsCmd      = flag.NewFlagSet("jenkins", flag.ExitOnError)
+	jenkinsUsername = jenkinsCmd.String("username", "", "The Jenkins user to authenticate as.")
+	jenkinsToken    = jenkinsCmd.String("token", "", "An API token of the Jenkins user.")
+	jenkinsURL      = jenkinsCmd.String("jenkins-url", "", "The target Jenkins URL.")
 
-	enrichCmd = flag.NewFlagSet("enrich", flag.ExitOnError)
+	buildkiteCmd          = flag.NewFlagSet("buildkite", flag.ExitOnError)
+	buildkiteToken        = buildkiteCmd.String("token", "", "A Buildkite API access token with GraphQL access.")
+	buildkiteOrganization = buildkiteCmd.String(
+		"buildkite-organization",
+		"",
+		"The Buildkite organization slug. Defaults to the GitHub organization.",
+	)
+	buildkiteGraphQLURL = buildkiteCmd.String("buildkite-graphql-url", "https://graphql.buildkite.com/v1", "The target Buildkite GraphQL URL.")
+	buildkiteRESTURL    = buildkiteCmd.String("buildkite-rest-url", "https://api.buildkite.com/v2", "The target Buildkite REST API URL.")
+
+	cloudCICmd               = flag.NewFlagSet("cloudci", flag.ExitOnError)
+	cloudCICodeBuildProjects = cloudCICmd.String(
+		"codebuild-projects",
+		"",
+		"Path to a JSON export of AWS CodeBuild projects, from 'aws codebuild batch-get-projects'.",
+	)
+	cloudCICloudBuildTriggers = cloudCICmd.String(
+		"cloudbuild-triggers",
+		"",
+		"Path to a JSON export of Google Cloud Build triggers, from 'gcloud builds triggers list --format=json'.",
+	)
+
+	enrichCmd   = flag.NewFlagSet("enrich", flag.ExitOnError)
+	enrichRules = enrichCmd.String(
+		"rules",
+		"",
+		"Path to a YAML or JSON enrichment rules file. Defaults to the built-in rules.",
+	)
+	enrichFull = enrichCmd.Bool(
+		"full",
+		false,
+		"Enrich all repositories, not only those whose files changed since they were last enriched.",
+	)
 
 	subcommands = map[string]*flag.FlagSet{
-		githubCmd.Name():   githubCmd,
-		circleCICmd.Name(): circleCICmd,
-		enrichCmd.Name():   enrichCmd,
+		githubCmd.Name():    githubCmd,
+		circleCICmd.Name():  circleCICmd,
+		travisCmd.Name():    travisCmd,
+		jenkinsCmd.Name():   jenkinsCmd,
+		buildkiteCmd.Name(): buildkiteCmd,
+		cloudCICmd.Name():   cloudCICmd,
+		enrichCmd.Name():    enrichCmd,
 	}
 )
 
@@ -64,14 +120,35 @@ func main() {
 	case circleCICmd.Name():
 		cmdCircleCI(cmd)
 
+	case travisCmd.Name():
+		cmdTravis(cmd)
+
+	case jenkinsCmd.Name():
+		cmdJenkins(cmd)
+
+	case buildkiteCmd.Name():
+		cmdBuildkite(cmd)
+
+	case cloudCICmd.Name():
+		cmdCloudCI(cmd)
+
 	case enrichCmd.Name():
 		setupCommonFlags()
 		cmd.Parse(os.Args[2:])
 		validateCommonParams()
 		initLogging()
 
+		rules := enrich.DefaultRules()
+		if *enrichRules != "" {
+			var err error
+			rules, err = enrich.LoadRules(*enrichRules)
+			if err != nil {
+				log.Fatalf("Error loading rules: %s", err)
+			}
+		}
+
 		db := database.GetDB(neo4jURI, neo4jUser, neo4jPassword)
-		en := enrich.GetEnricher(db, organization)
+		en := enrich.GetEnricher(db, organization, session, rules, *enrichFull)
 		en.Enrich()
 
 	default:
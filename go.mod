@@ -6,4 +6,5 @@ require (
 	github.com/Jeffail/gabs/v2 v2.6.1
 	github.com/neo4j/neo4j-go-driver/v4 v4.3.2
 	github.com/sirupsen/logrus v1.8.1
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Matches scp-like Git URLs, e.g. git@github.com:fakenews/some-repo.git.
+var scpURLRegex = regexp.MustCompile(`^[\w.-]+@([^:/]+):(.+)$`)
+
+// Splits a Git remote URL into its host and the path of the repository, without the .git suffix.
+// The host is empty for remotes we can't parse, which are returned as the path.
+func splitRemoteURL(remote string) (string, string) {
+	host, repoPath := "", remote
+	if match := scpURLRegex.FindStringSubmatch(remote); match != nil {
+		host, repoPath = match[1], match[2]
+	} else if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		host, repoPath = u.Hostname(), u.Path
+	}
+	return host, strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+}
+
+// Normalizes a Git remote URL to the web URL of the repository, which is how GitHub repositories
+// are identified, e.g. git@github.com:fakenews/some-repo.git to https://github.com/fakenews/some-repo.
+// Remotes we can't parse are returned as is.
+func RepositoryURL(remote string) string {
+	host, repoPath := splitRemoteURL(remote)
+	if host == "" {
+		return remote
+	}
+	return "https://" + host + "/" + repoPath
+}
+
+// Returns the "owner/name" path of a repository given its Git remote URL, for sources that don't
+// always tell the host (e.g. GitHub Enterprise).
+func RepositoryPath(remote string) string {
+	_, repoPath := splitRemoteURL(remote)
+	return repoPath
+}
@@ -0,0 +1,14 @@
+package utils
+
+// Returns slice s with duplicates removed, keeping the first occurrence of each string.
+func RemoveDuplicateStrings(s []string) []string {
+	keys := make(map[string]bool)
+	r := []string{}
+	for _, e := range s {
+		if _, seen := keys[e]; !seen {
+			keys[e] = true
+			r = append(r, e)
+		}
+	}
+	return r
+}
@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemoveDuplicateStrings(t *testing.T) {
+	got := RemoveDuplicateStrings([]string{"b", "a", "b", "c", "a"})
+	if want := []string{"b", "a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RemoveDuplicateStrings() = %v, want %v", got, want)
+	}
+	if got := RemoveDuplicateStrings(nil); got == nil || len(got) != 0 {
+		t.Errorf("RemoveDuplicateStrings(nil) = %#v, want an empty slice", got)
+	}
+}
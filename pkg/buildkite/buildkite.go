@@ -0,0 +1,97 @@
+package buildkite
+
+import (
+	"net/http"
+
+	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
+)
+
+type Buildkite struct {
+	gqlclient  *GraphQLClient
+	restclient *RESTClient
+	db         *database.Database
+	// the GitHub organization, whose repositories and teams we link to
+	organization string
+	// the Buildkite organization slug
+	buildkiteOrganization string
+	session               string
+}
+
+// Returns a Buildkite ingestor for a Buildkite organization, authenticating with an API access
+// token. We link to the repositories and teams of the GitHub organization.
+func GetBuildkite(
+	db *database.Database,
+	graphQLURL, restURL, organization, buildkiteOrganization, token, session string,
+) *Buildkite {
+	return &Buildkite{
+		gqlclient: &GraphQLClient{
+			client:       &http.Client{},
+			token:        token,
+			organization: buildkiteOrganization,
+			graphQLURL:   graphQLURL,
+		},
+		restclient: &RESTClient{
+			client:  &http.Client{},
+			baseURL: restURL,
+			token:   token,
+		},
+		db:                    db,
+		organization:          organization,
+		buildkiteOrganization: buildkiteOrganization,
+		session:               session,
+	}
+}
+
+func (bk *Buildkite) Sync() {
+	log.Info("Running OrganizationIngestor")
+	oi := OrganizationIngestor{
+		gqlclient: bk.gqlclient,
+		db:        bk.db,
+		data:      &OrganizationData{},
+		session:   bk.session,
+	}
+	oi.Sync()
+
+	log.Info("Running ClustersIngestor")
+	ci := ClustersIngestor{
+		gqlclient:             bk.gqlclient,
+		db:                    bk.db,
+		data:                  &ClustersData{},
+		buildkiteOrganization: bk.buildkiteOrganization,
+		session:               bk.session,
+	}
+	ci.Sync()
+
+	log.Info("Running PipelinesIngestor")
+	pi := PipelinesIngestor{
+		gqlclient:             bk.gqlclient,
+		db:                    bk.db,
+		data:                  &PipelinesData{},
+		organization:          bk.organization,
+		buildkiteOrganization: bk.buildkiteOrganization,
+		session:               bk.session,
+	}
+	pi.Sync()
+
+	log.Info("Running PipelineSettingsIngestor")
+	psi := PipelineSettingsIngestor{
+		restclient:            bk.restclient,
+		db:                    bk.db,
+		data:                  &PipelineSettingsData{},
+		buildkiteOrganization: bk.buildkiteOrganization,
+		session:               bk.session,
+	}
+	psi.Sync()
+
+	log.Info("Running TeamsIngestor")
+	ti := TeamsIngestor{
+		gqlclient:             bk.gqlclient,
+		db:                    bk.db,
+		data:                  &TeamsData{},
+		organization:          bk.organization,
+		buildkiteOrganization: bk.buildkiteOrganization,
+		session:               bk.session,
+	}
+	ti.Sync()
+}
@@ -0,0 +1,157 @@
+package buildkite
+
+import (
+	"encoding/json"
+
+	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
+)
+
+// Ingests the organization's clusters and their agent queues. Queues are Runner nodes, like the
+// CircleCI self-hosted runners' resource classes, as whoever can run builds on a queue can run code
+// on its agents.
+type ClustersIngestor struct {
+	gqlclient             *GraphQLClient
+	db                    *database.Database
+	data                  *ClustersData
+	buildkiteOrganization string
+	session               string
+}
+
+type ClustersData struct {
+	Edges []struct {
+		Node struct {
+			UUID        string `json:"uuid"`
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			Queues      struct {
+				PageInfo struct {
+					HasNextPage bool `json:"hasNextPage"`
+				} `json:"pageInfo"`
+				Edges []struct {
+					Node struct {
+						UUID        string `json:"uuid"`
+						Key         string `json:"key"`
+						Description string `json:"description"`
+					} `json:"node"`
+				} `json:"edges"`
+			} `json:"queues"`
+		} `json:"node"`
+	} `json:"edges"`
+}
+
+func (ing *ClustersIngestor) fetchData() {
+	query := `
+	query($slug: ID!, $cursor: String) {
+		organization(slug: $slug) {
+			clusters(first: 100, after: $cursor) {
+				pageInfo {
+					endCursor
+					hasNextPage
+				}
+				edges {
+					node {
+						uuid
+						name
+						description
+						queues(first: 100) {
+							pageInfo {
+								hasNextPage
+							}
+							edges {
+								node {
+									uuid
+									key
+									description
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	`
+
+	data := ing.gqlclient.fetch(query, "organization.clusters", map[string]string{})
+
+	json.Unmarshal(data, &ing.data)
+}
+
+func (ing *ClustersIngestor) Sync() {
+	ing.fetchData()
+	ing.insertClusters()
+	ing.insertClustersQueues()
+}
+
+func (ing *ClustersIngestor) insertClusters() {
+	clusters := []map[string]interface{}{}
+
+	for _, clusterEdge := range ing.data.Edges {
+		clusters = append(clusters, map[string]interface{}{
+			"uuid":        clusterEdge.Node.UUID,
+			"name":        clusterEdge.Node.Name,
+			"description": clusterEdge.Node.Description,
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $clusters AS cluster
+
+	MERGE (c:BuildkiteCluster{id: cluster.uuid})
+
+	SET c.name = cluster.name,
+	c.description = cluster.description,
+	c.organization = $buildkiteOrganization,
+	c.session = $session
+
+	WITH c
+
+	MATCH (o:BuildkiteOrganization{id: $buildkiteOrganization})
+	MERGE (c)-[rel:OWNED_BY]->(o)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"clusters":              clusters,
+		"buildkiteOrganization": ing.buildkiteOrganization,
+		"session":               ing.session,
+	})
+}
+
+func (ing *ClustersIngestor) insertClustersQueues() {
+	queues := []map[string]interface{}{}
+
+	for _, clusterEdge := range ing.data.Edges {
+		if clusterEdge.Node.Queues.PageInfo.HasNextPage {
+			log.Warnf(
+				"Cluster %s has more than 100 queues, only the first 100 are ingested",
+				clusterEdge.Node.Name,
+			)
+		}
+		for _, queueEdge := range clusterEdge.Node.Queues.Edges {
+			queues = append(queues, map[string]interface{}{
+				"uuid":        queueEdge.Node.UUID,
+				"key":         queueEdge.Node.Key,
+				"description": queueEdge.Node.Description,
+				"clusterUUID": clusterEdge.Node.UUID,
+			})
+		}
+	}
+
+	ing.db.Run(`
+	UNWIND $queues AS queue
+
+	MERGE (q:Runner{id: queue.uuid})
+
+	SET q:BuildkiteQueue,
+	q.key = queue.key,
+	q.description = queue.description,
+	q.platform = "buildkite",
+	q.session = $session
+
+	WITH q, queue
+
+	MATCH (c:BuildkiteCluster{id: queue.clusterUUID})
+	MERGE (c)-[rel:HAS_QUEUE]->(q)
+	SET rel.session = $session
+	`, map[string]interface{}{"queues": queues, "session": ing.session})
+}
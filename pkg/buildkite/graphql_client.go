@@ -0,0 +1,193 @@
+package buildkite
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	gabs "github.com/Jeffail/gabs/v2"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	fatalErrors = []string{
+		"Your API Access Token doesn't have the `graphql` scope",
+		"No organization found",
+	}
+)
+
+type GraphQLClient struct {
+	client       *http.Client
+	token        string
+	organization string
+	graphQLURL   string
+}
+
+type GraphQLError struct {
+	message string
+	count   int
+}
+
+// Retrieves a single page for the GraphQL query.
+func (c *GraphQLClient) call(query string, variables map[string]string) []byte {
+	jsonValue, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	log.Debugf("Issuing GraphQL query: %v", string(jsonValue))
+
+	req, err := http.NewRequest(
+		"POST",
+		c.graphQLURL,
+		bytes.NewBuffer(jsonValue),
+	)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	req.Header.Add("Authorization", "Bearer "+c.token)
+	req.Header.Add("content-type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	switch resp.StatusCode {
+	case 200:
+		// All good, do nothing
+	default:
+		log.Panicf(
+			"Received HTTP status code %d from GraphQL API.",
+			resp.StatusCode,
+		)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return body
+}
+
+// Retrieves all pages for the GraphQL query. Buildkite connections wrap nodes in edges, which are
+// merged across pages.
+func (c *GraphQLClient) fetch(query, resourcePath string, variables map[string]string) []byte {
+	hasNextPagePath := fmt.Sprintf("data.%s.pageInfo.hasNextPage", resourcePath)
+	cursorPath := fmt.Sprintf("data.%s.pageInfo.endCursor", resourcePath)
+	dataPath := fmt.Sprintf("data.%s", resourcePath)
+
+	variables["slug"] = c.organization
+
+	data := gabs.Container{}
+	errorTracker := map[string]GraphQLError{}
+	for {
+		resp := c.call(query, variables)
+
+		parsedResp, err := gabs.ParseJSON(resp)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		// track GraphQL errors for diagnostics
+		gqlerrors := parsedResp.Path("errors")
+		if gqlerrors != nil {
+			c.checkFatalErrors(gqlerrors)
+			c.trackFetchErrors(gqlerrors, errorTracker)
+		}
+
+		// Merge this page's data into all data
+		d := parsedResp.Path(dataPath)
+		data.Merge(d)
+
+		// Handle pagination
+		hasNextPageData := parsedResp.Path(hasNextPagePath).Data()
+		if hasNextPageData == nil {
+			log.Warnf(
+				"No hasNextPage in pageInfo for %s, something is wrong",
+				resourcePath,
+			)
+			break
+		}
+		hasNextPage := hasNextPageData.(bool)
+		if !hasNextPage {
+			break
+		}
+
+		cursor := parsedResp.Path(cursorPath).Data().(string)
+		variables["cursor"] = cursor
+	}
+
+	c.logFetchErrors(resourcePath, errorTracker)
+
+	return data.Bytes()
+}
+
+// Retrieves data in resourcePath for a GraphQL query that isn't paginated.
+func (c *GraphQLClient) query(query, resourcePath string, variables map[string]string) []byte {
+	variables["slug"] = c.organization
+
+	resp := c.call(query, variables)
+
+	parsedResp, err := gabs.ParseJSON(resp)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	gqlerrors := parsedResp.Path("errors")
+	if gqlerrors != nil {
+		c.checkFatalErrors(gqlerrors)
+		errorTracker := map[string]GraphQLError{}
+		c.trackFetchErrors(gqlerrors, errorTracker)
+		c.logFetchErrors(resourcePath, errorTracker)
+	}
+
+	return parsedResp.Path(fmt.Sprintf("data.%s", resourcePath)).Bytes()
+}
+
+// Takes errors container received from a GraphQL JSON response and updates the errorTracker.
+func (c *GraphQLClient) trackFetchErrors(
+	errors *gabs.Container,
+	errorTracker map[string]GraphQLError,
+) {
+	for _, e := range errors.Children() {
+		message, _ := e.Path("message").Data().(string)
+		if gqlerror, ok := errorTracker[message]; ok {
+			gqlerror.count++
+			errorTracker[message] = gqlerror
+		} else {
+			errorTracker[message] = GraphQLError{
+				message: message,
+				count:   1,
+			}
+		}
+	}
+}
+
+// Checks error container received from a GraphQL JSON response for fatal errors that should stop
+// execution.
+func (c *GraphQLClient) checkFatalErrors(errors *gabs.Container) {
+	for _, e := range errors.Children() {
+		message, _ := e.Path("message").Data().(string)
+		for _, fatalError := range fatalErrors {
+			if strings.Contains(message, fatalError) {
+				log.Fatalf("Fatal GraphQL error received from Buildkite: %s", message)
+			}
+		}
+	}
+}
+
+// Logs GraphQL errors to output.
+func (c *GraphQLClient) logFetchErrors(resourcePath string, errorTracker map[string]GraphQLError) {
+	for _, e := range errorTracker {
+		log.Warnf("%d errors on %s: %s", e.count, resourcePath, e.message)
+	}
+}
@@ -0,0 +1,53 @@
+package buildkite
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGraphQLClientFetch(t *testing.T) {
+	pages := map[string]string{
+		"": `{"data": {"organization": {"clusters": {
+			"pageInfo": {"endCursor": "c1", "hasNextPage": true},
+			"edges": [{"node": {"uuid": "cluster-1"}}]
+		}}}}`,
+		"c1": `{"data": {"organization": {"clusters": {
+			"pageInfo": {"endCursor": "c2", "hasNextPage": false},
+			"edges": [{"node": {"uuid": "cluster-2"}}]
+		}}}}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Variables map[string]string `json:"variables"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.Header.Get("Authorization") != "Bearer fake" || body.Variables["slug"] != "fakenews" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, pages[body.Variables["cursor"]])
+	}))
+	defer server.Close()
+
+	c := &GraphQLClient{
+		client:       &http.Client{},
+		token:        "fake",
+		organization: "fakenews",
+		graphQLURL:   server.URL,
+	}
+	data := ClustersData{}
+	json.Unmarshal(c.fetch("query", "organization.clusters", map[string]string{}), &data)
+
+	got := []string{}
+	for _, edge := range data.Edges {
+		got = append(got, edge.Node.UUID)
+	}
+	if want := []string{"cluster-1", "cluster-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fetch() clusters = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,57 @@
+package buildkite
+
+import (
+	"encoding/json"
+
+	"github.com/ovotech/gitoops/pkg/database"
+)
+
+type OrganizationIngestor struct {
+	gqlclient *GraphQLClient
+	db        *database.Database
+	data      *OrganizationData
+	session   string
+}
+
+type OrganizationData struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+func (ing *OrganizationIngestor) fetchData() {
+	query := `
+	query($slug: ID!) {
+		organization(slug: $slug) {
+			uuid
+			name
+			slug
+		}
+	}
+	`
+
+	data := ing.gqlclient.query(query, "organization", map[string]string{})
+
+	json.Unmarshal(data, &ing.data)
+}
+
+func (ing *OrganizationIngestor) Sync() {
+	ing.fetchData()
+	ing.insertOrganization()
+}
+
+func (ing *OrganizationIngestor) insertOrganization() {
+	ing.db.Run(`
+	MERGE (o:BuildkiteOrganization{id: $slug})
+
+	SET o.uuid = $uuid,
+	o.name = $name,
+	o.slug = $slug,
+	o.session = $session
+	`, map[string]interface{}{
+		"slug":    ing.data.Slug,
+		"uuid":    ing.data.UUID,
+		"name":    ing.data.Name,
+		"session": ing.session,
+	})
+}
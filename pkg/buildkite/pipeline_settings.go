@@ -0,0 +1,88 @@
+package buildkite
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ovotech/gitoops/pkg/database"
+)
+
+// Ingests pipelines' repository provider settings, which the GraphQL API doesn't expose, most
+// importantly whether they build pull requests from forks.
+type PipelineSettingsIngestor struct {
+	restclient            *RESTClient
+	db                    *database.Database
+	data                  *PipelineSettingsData
+	buildkiteOrganization string
+	session               string
+}
+
+type PipelineSettingsData []struct {
+	Slug     string `json:"slug"`
+	Provider struct {
+		ID       string `json:"id"`
+		Settings struct {
+			TriggerMode                   string `json:"trigger_mode"`
+			BuildPullRequests             bool   `json:"build_pull_requests"`
+			BuildPullRequestForks         bool   `json:"build_pull_request_forks"`
+			PrefixPullRequestForkBranches bool   `json:"prefix_pull_request_fork_branch_names"`
+		} `json:"settings"`
+	} `json:"provider"`
+}
+
+func (ing *PipelineSettingsIngestor) fetchData() {
+	query := fmt.Sprintf("organizations/%s/pipelines", ing.buildkiteOrganization)
+	data := ing.restclient.fetch(query)
+	json.Unmarshal(data, &ing.data)
+}
+
+func (ing *PipelineSettingsIngestor) Sync() {
+	ing.fetchData()
+	ing.insertPipelineSettings()
+}
+
+func (ing *PipelineSettingsIngestor) insertPipelineSettings() {
+	pipelines := []map[string]interface{}{}
+
+	for _, pipeline := range *ing.data {
+		settings := pipeline.Provider.Settings
+		pipelines = append(pipelines, map[string]interface{}{
+			"slug":                             pipeline.Slug,
+			"provider":                         pipeline.Provider.ID,
+			"triggerMode":                      settings.TriggerMode,
+			"buildPullRequests":                settings.BuildPullRequests,
+			"buildPullRequestForks":            settings.BuildPullRequestForks,
+			"prefixPullRequestForkBranchNames": settings.PrefixPullRequestForkBranches,
+		})
+	}
+
+	// anyone able to fork the repository can open a pull request running their code on the
+	// pipeline's agents, with whatever secrets their environment hooks expose
+	ing.db.Run(`
+	UNWIND $pipelines AS pipeline
+
+	MATCH (p:BuildkitePipeline{slug: pipeline.slug, organization: $buildkiteOrganization})
+
+	SET p.provider = pipeline.provider,
+	p.triggerMode = pipeline.triggerMode,
+	p.buildPullRequests = pipeline.buildPullRequests,
+	p.buildPullRequestForks = pipeline.buildPullRequestForks,
+	p.prefixPullRequestForkBranchNames = pipeline.prefixPullRequestForkBranchNames
+
+	WITH p, pipeline
+
+	OPTIONAL MATCH (:Anyone)-[rel:CAN_RUN_CODE_WITH_SECRETS]->(p)
+	DELETE rel
+
+	WITH p, pipeline
+	WHERE pipeline.buildPullRequests AND pipeline.buildPullRequestForks
+
+	MERGE (a:Anyone{id: "anyone"})
+	MERGE (a)-[rel:CAN_RUN_CODE_WITH_SECRETS]->(p)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"pipelines":             pipelines,
+		"buildkiteOrganization": ing.buildkiteOrganization,
+		"session":               ing.session,
+	})
+}
@@ -0,0 +1,170 @@
+package buildkite
+
+import (
+	"encoding/json"
+
+	"github.com/ovotech/gitoops/internal/utils"
+	"github.com/ovotech/gitoops/pkg/database"
+)
+
+type PipelinesIngestor struct {
+	gqlclient             *GraphQLClient
+	db                    *database.Database
+	data                  *PipelinesData
+	organization          string
+	buildkiteOrganization string
+	session               string
+}
+
+type PipelinesData struct {
+	Edges []struct {
+		Node struct {
+			UUID          string `json:"uuid"`
+			Name          string `json:"name"`
+			Slug          string `json:"slug"`
+			URL           string `json:"url"`
+			Visibility    string `json:"visibility"`
+			DefaultBranch string `json:"defaultBranch"`
+			Repository    struct {
+				URL string `json:"url"`
+			} `json:"repository"`
+			Cluster struct {
+				UUID string `json:"uuid"`
+			} `json:"cluster"`
+		} `json:"node"`
+	} `json:"edges"`
+}
+
+func (ing *PipelinesIngestor) fetchData() {
+	query := `
+	query($slug: ID!, $cursor: String) {
+		organization(slug: $slug) {
+			pipelines(first: 100, after: $cursor) {
+				pageInfo {
+					endCursor
+					hasNextPage
+				}
+				edges {
+					node {
+						uuid
+						name
+						slug
+						url
+						visibility
+						defaultBranch
+						repository {
+							url
+						}
+						cluster {
+							uuid
+						}
+					}
+				}
+			}
+		}
+	}
+	`
+
+	data := ing.gqlclient.fetch(query, "organization.pipelines", map[string]string{})
+
+	json.Unmarshal(data, &ing.data)
+}
+
+func (ing *PipelinesIngestor) Sync() {
+	ing.fetchData()
+	ing.insertPipelines()
+	ing.insertPipelinesRepos()
+	ing.insertPipelinesClusters()
+}
+
+func (ing *PipelinesIngestor) insertPipelines() {
+	pipelines := []map[string]interface{}{}
+
+	for _, pipelineEdge := range ing.data.Edges {
+		pipeline := pipelineEdge.Node
+		pipelines = append(pipelines, map[string]interface{}{
+			"uuid":          pipeline.UUID,
+			"name":          pipeline.Name,
+			"slug":          pipeline.Slug,
+			"url":           pipeline.URL,
+			"visibility":    pipeline.Visibility,
+			"defaultBranch": pipeline.DefaultBranch,
+			"repositoryURL": pipeline.Repository.URL,
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $pipelines AS pipeline
+
+	MERGE (p:BuildkitePipeline{id: pipeline.uuid})
+
+	SET p.name = pipeline.name,
+	p.slug = pipeline.slug,
+	p.url = pipeline.url,
+	p.visibility = pipeline.visibility,
+	p.defaultBranch = pipeline.defaultBranch,
+	p.repositoryURL = pipeline.repositoryURL,
+	p.organization = $buildkiteOrganization,
+	p.session = $session
+
+	WITH p
+
+	MATCH (o:BuildkiteOrganization{id: $buildkiteOrganization})
+	MERGE (p)-[rel:OWNED_BY]->(o)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"pipelines":             pipelines,
+		"buildkiteOrganization": ing.buildkiteOrganization,
+		"session":               ing.session,
+	})
+}
+
+// Pipelines are linked to the organization's repositories they build, matched on their repository
+// URL.
+func (ing *PipelinesIngestor) insertPipelinesRepos() {
+	pipelines := []map[string]interface{}{}
+
+	for _, pipelineEdge := range ing.data.Edges {
+		pipelines = append(pipelines, map[string]interface{}{
+			"uuid":          pipelineEdge.Node.UUID,
+			"repositoryURL": utils.RepositoryURL(pipelineEdge.Node.Repository.URL),
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $pipelines AS pipeline
+
+	MATCH (p:BuildkitePipeline{id: pipeline.uuid})
+	MATCH (r:Repository)-[:OWNED_BY]->(:Organization{login: $organization})
+	WHERE toLower(r.id) = toLower(pipeline.repositoryURL)
+	MERGE (r)-[rel:HAS_CI]->(p)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"pipelines":    pipelines,
+		"organization": ing.organization,
+		"session":      ing.session,
+	})
+}
+
+func (ing *PipelinesIngestor) insertPipelinesClusters() {
+	pipelines := []map[string]interface{}{}
+
+	for _, pipelineEdge := range ing.data.Edges {
+		if pipelineEdge.Node.Cluster.UUID == "" {
+			continue
+		}
+		pipelines = append(pipelines, map[string]interface{}{
+			"uuid":        pipelineEdge.Node.UUID,
+			"clusterUUID": pipelineEdge.Node.Cluster.UUID,
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $pipelines AS pipeline
+
+	MATCH (p:BuildkitePipeline{id: pipeline.uuid})
+	MATCH (c:BuildkiteCluster{id: pipeline.clusterUUID})
+	MERGE (p)-[rel:USES_CLUSTER]->(c)
+	SET rel.session = $session
+	`, map[string]interface{}{"pipelines": pipelines, "session": ing.session})
+}
@@ -0,0 +1,84 @@
+package buildkite
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	gabs "github.com/Jeffail/gabs/v2"
+	log "github.com/sirupsen/logrus"
+)
+
+type RESTClient struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+// Retrieves a single page for a REST query.
+func (c *RESTClient) call(resourcePath string, page int) (int, []byte) {
+	log.Debugf("Issuing REST query %s page %d", resourcePath, page)
+
+	u, _ := url.Parse(c.baseURL)
+	u.Path = path.Join(u.Path, resourcePath)
+	req, err := http.NewRequest(
+		"GET",
+		u.String(),
+		nil,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Add("Authorization", "Bearer "+c.token)
+
+	q := req.URL.Query()
+	q.Add("per_page", "100")
+	q.Add("page", fmt.Sprint(page))
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		panic(err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		panic(err)
+	}
+
+	return resp.StatusCode, body
+}
+
+// Retrieves all pages of a REST list, stopping at the first empty page.
+func (c *RESTClient) fetch(resourcePath string) []byte {
+	data := gabs.New()
+	data.Array()
+
+	for page := 1; ; page++ {
+		code, resp := c.call(resourcePath, page)
+
+		parsedResp, err := gabs.ParseJSON(resp)
+		if err != nil {
+			panic(err)
+		}
+
+		if code != 200 {
+			message, _ := parsedResp.Path("message").Data().(string)
+			log.Warnf("%d error on %s: %s", code, resourcePath, message)
+			break
+		}
+
+		items := parsedResp.Children()
+		if len(items) == 0 {
+			break
+		}
+		for _, item := range items {
+			data.ArrayAppend(item.Data())
+		}
+	}
+
+	return data.Bytes()
+}
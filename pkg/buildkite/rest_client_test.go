@@ -0,0 +1,45 @@
+package buildkite
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRESTClientFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/organizations/fakenews/pipelines" || r.Header.Get("Authorization") != "Bearer fake" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+			return
+		}
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprint(w, `[{"slug": "api"}, {"slug": "web"}]`)
+		case "2":
+			fmt.Fprint(w, `[{"slug": "aws-infra"}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	c := &RESTClient{client: &http.Client{}, baseURL: server.URL + "/v2", token: "fake"}
+	data := PipelineSettingsData{}
+	json.Unmarshal(c.fetch("organizations/fakenews/pipelines"), &data)
+
+	got := []string{}
+	for _, pipeline := range data {
+		got = append(got, pipeline.Slug)
+	}
+	if want := []string{"api", "web", "aws-infra"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fetch() pipelines = %v, want %v", got, want)
+	}
+
+	if got := string(c.fetch("organizations/missing/pipelines")); got != "[]" {
+		t.Errorf("fetch() of a missing resource = %s, want []", got)
+	}
+}
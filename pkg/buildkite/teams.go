@@ -0,0 +1,169 @@
+package buildkite
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
+)
+
+// Ingests the organization's teams and the pipelines they can access. Buildkite teams aren't
+// synced with GitHub, so we link them to the GitHub organization's teams of the same slug, which is
+// how teams are usually mirrored.
+type TeamsIngestor struct {
+	gqlclient             *GraphQLClient
+	db                    *database.Database
+	data                  *TeamsData
+	organization          string
+	buildkiteOrganization string
+	session               string
+}
+
+type TeamsData struct {
+	Edges []struct {
+		Node struct {
+			UUID      string `json:"uuid"`
+			Name      string `json:"name"`
+			Slug      string `json:"slug"`
+			Privacy   string `json:"privacy"`
+			Pipelines struct {
+				PageInfo struct {
+					HasNextPage bool `json:"hasNextPage"`
+				} `json:"pageInfo"`
+				Edges []struct {
+					Node struct {
+						AccessLevel string `json:"accessLevel"`
+						Pipeline    struct {
+							UUID string `json:"uuid"`
+						} `json:"pipeline"`
+					} `json:"node"`
+				} `json:"edges"`
+			} `json:"pipelines"`
+		} `json:"node"`
+	} `json:"edges"`
+}
+
+func (ing *TeamsIngestor) fetchData() {
+	query := `
+	query($slug: ID!, $cursor: String) {
+		organization(slug: $slug) {
+			teams(first: 50, after: $cursor) {
+				pageInfo {
+					endCursor
+					hasNextPage
+				}
+				edges {
+					node {
+						uuid
+						name
+						slug
+						privacy
+						pipelines(first: 100) {
+							pageInfo {
+								hasNextPage
+							}
+							edges {
+								node {
+									accessLevel
+									pipeline {
+										uuid
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	`
+
+	data := ing.gqlclient.fetch(query, "organization.teams", map[string]string{})
+
+	json.Unmarshal(data, &ing.data)
+}
+
+func (ing *TeamsIngestor) Sync() {
+	ing.fetchData()
+	ing.insertTeams()
+	ing.insertTeamsPipelines()
+}
+
+func (ing *TeamsIngestor) insertTeams() {
+	teams := []map[string]interface{}{}
+
+	for _, teamEdge := range ing.data.Edges {
+		team := teamEdge.Node
+		teams = append(teams, map[string]interface{}{
+			"uuid":    team.UUID,
+			"name":    team.Name,
+			"slug":    team.Slug,
+			"privacy": team.Privacy,
+			"teamPath": fmt.Sprintf(
+				"/orgs/%s/teams/%s",
+				strings.ToLower(ing.organization),
+				strings.ToLower(team.Slug),
+			),
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $teams AS team
+
+	MERGE (t:BuildkiteTeam{id: team.uuid})
+
+	SET t.name = team.name,
+	t.slug = team.slug,
+	t.privacy = team.privacy,
+	t.organization = $buildkiteOrganization,
+	t.session = $session
+
+	WITH t, team
+
+	MATCH (o:BuildkiteOrganization{id: $buildkiteOrganization})
+	MERGE (t)-[rel:OWNED_BY]->(o)
+	SET rel.session = $session
+
+	WITH t, team
+
+	MATCH (g:Team)
+	WHERE toLower(g.url) ENDS WITH team.teamPath
+	MERGE (g)-[rel:IS_BUILDKITE_TEAM]->(t)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"teams":                 teams,
+		"buildkiteOrganization": ing.buildkiteOrganization,
+		"session":               ing.session,
+	})
+}
+
+func (ing *TeamsIngestor) insertTeamsPipelines() {
+	teamsPipelines := []map[string]interface{}{}
+
+	for _, teamEdge := range ing.data.Edges {
+		if teamEdge.Node.Pipelines.PageInfo.HasNextPage {
+			log.Warnf(
+				"Team %s can access more than 100 pipelines, only the first 100 are ingested",
+				teamEdge.Node.Slug,
+			)
+		}
+		for _, pipelineEdge := range teamEdge.Node.Pipelines.Edges {
+			teamsPipelines = append(teamsPipelines, map[string]interface{}{
+				"teamUUID":     teamEdge.Node.UUID,
+				"pipelineUUID": pipelineEdge.Node.Pipeline.UUID,
+				"accessLevel":  pipelineEdge.Node.AccessLevel,
+			})
+		}
+	}
+
+	ing.db.Run(`
+	UNWIND $teamsPipelines AS teamPipeline
+
+	MATCH (t:BuildkiteTeam{id: teamPipeline.teamUUID})
+	MATCH (p:BuildkitePipeline{id: teamPipeline.pipelineUUID})
+	MERGE (t)-[rel:HAS_PERMISSION_ON{accessLevel: teamPipeline.accessLevel}]->(p)
+	SET rel.session = $session
+	`, map[string]interface{}{"teamsPipelines": teamsPipelines, "session": ing.session})
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strings"
 
 	"github.com/ovotech/gitoops/pkg/database"
 	log "github.com/sirupsen/logrus"
@@ -17,7 +18,9 @@ type CircleCI struct {
 	session      string
 }
 
-func GetCircleCI(db *database.Database, organization, cookie, session string) *CircleCI {
+// Returns a CircleCI ingestor for the CircleCI instance at baseURL. We authenticate with a personal
+// API token if given, or a browser session cookie otherwise.
+func GetCircleCI(db *database.Database, baseURL, organization, cookie, token, session string) *CircleCI {
 	// Make sure cookie value is URL encoded by checking it doesn't have characters we'd expect to
 	// be encoded.
 	re := regexp.MustCompile(`(\+|\/|-|=| )`)
@@ -26,14 +29,25 @@ func GetCircleCI(db *database.Database, organization, cookie, session string) *C
 		cookie = url.QueryEscape(cookie)
 	}
 
+	// CircleCI server hosts the runner API itself
+	runnerURL := strings.TrimSuffix(baseURL, "/")
+	if runnerURL == "https://circleci.com" {
+		runnerURL = "https://runner.circleci.com"
+	}
+
 	cci := &CircleCI{
 		gqlclient: &GraphQLClient{
-			client: &http.Client{},
-			cookie: cookie,
+			client:  &http.Client{},
+			baseURL: baseURL,
+			cookie:  cookie,
+			token:   token,
 		},
 		restclient: &RESTClient{
-			client: &http.Client{},
-			cookie: cookie,
+			client:    &http.Client{},
+			baseURL:   strings.TrimSuffix(baseURL, "/"),
+			runnerURL: runnerURL,
+			cookie:    cookie,
+			token:     token,
 		},
 		db:           db,
 		organization: organization,
@@ -53,12 +67,14 @@ func (cci *CircleCI) Sync() {
 		session:      cci.session,
 	}
 	organizationId := oi.GetOrganizationId()
+	namespace := oi.GetNamespace()
 
 	log.Info("Running ContextsIngestor")
 	ci := ContextsIngestor{
 		gqlclient:      cci.gqlclient,
 		db:             cci.db,
 		data:           &ContextsData{},
+		organization:   cci.organization,
 		organizationId: organizationId,
 		session:        cci.session,
 	}
@@ -83,43 +99,96 @@ func (cci *CircleCI) Sync() {
 		cevi.Sync()
 	}
 
-	// repoIngestors query at a specific repo level
-	repoRecords := cci.db.Run(
-		`MATCH (r:Repository{session:$session}) RETURN r.name as repoName`,
+	log.Info("Running ProjectsIngestor")
+	pi := ProjectsIngestor{
+		restclient:     cci.restclient,
+		db:             cci.db,
+		data:           &ProjectsData{},
+		organization:   cci.organization,
+		organizationId: organizationId,
+		session:        cci.session,
+	}
+	pi.Sync()
+
+	if namespace != "" {
+		log.Infof("Running RunnersIngestor on namespace %s", namespace)
+		ri := RunnersIngestor{
+			restclient: cci.restclient,
+			db:         cci.db,
+			data:       &RunnersData{},
+			namespace:  namespace,
+			session:    cci.session,
+		}
+		ri.Sync()
+	}
+
+	// restrictions refer to projects so we run these once projects are ingested
+	contextRecords = cci.db.Run(
+		`MATCH (c:CircleCIContext{session:$session}) RETURN c.id as contextId, c.name as contextName`,
 		map[string]interface{}{"session": cci.session},
 	)
-	for repoRecords.Next() {
-		repoName, _ := repoRecords.Record().Get("repoName")
+	for contextRecords.Next() {
+		contextId, _ := contextRecords.Record().Get("contextId")
+		contextName, _ := contextRecords.Record().Get("contextName")
 
-		log.Infof("Running ProjectIngestor on repo %s", repoName)
-		pi := ProjectIngestor{
+		log.Infof("Running ContextRestrictionsIngestor on context %s (%s)", contextName, contextId)
+		cri := ContextRestrictionsIngestor{
 			restclient:   cci.restclient,
 			db:           cci.db,
-			data:         &ProjectData{},
+			data:         &ContextRestrictionsData{},
 			organization: cci.organization,
-			repoName:     repoName.(string),
+			contextId:    contextId.(string),
 			session:      cci.session,
 		}
-		pi.Sync()
+		cri.Sync()
 	}
 
 	// queries existing CircleCIProjects
 	projectRecords := cci.db.Run(
-		`MATCH (p:CircleCIProject{session:$session}) RETURN p.repository as projectName`,
-		map[string]interface{}{"session": cci.session},
+		`MATCH (p:CircleCIProject{session:$session, organization:$organization}) RETURN p.id as projectSlug`,
+		map[string]interface{}{"session": cci.session, "organization": cci.organization},
 	)
 	for projectRecords.Next() {
-		projectName, _ := projectRecords.Record().Get("projectName")
+		projectSlug, _ := projectRecords.Record().Get("projectSlug")
 
-		log.Infof("Running ProjectEnvVarsIngestor on projet %s", projectName)
+		log.Infof("Running ProjectEnvVarsIngestor on project %s", projectSlug)
 		pevi := ProjectEnvVarsIngestor{
-			restclient:   cci.restclient,
-			db:           cci.db,
-			data:         &ProjectEnvVarsData{},
-			organization: cci.organization,
-			projectName:  projectName.(string),
-			session:      cci.session,
+			restclient:  cci.restclient,
+			db:          cci.db,
+			data:        &ProjectEnvVarsData{},
+			projectSlug: projectSlug.(string),
+			session:     cci.session,
 		}
 		pevi.Sync()
+
+		log.Infof("Running ProjectSettingsIngestor on project %s", projectSlug)
+		psi := ProjectSettingsIngestor{
+			restclient:  cci.restclient,
+			db:          cci.db,
+			data:        &ProjectSettingsData{},
+			projectSlug: projectSlug.(string),
+			session:     cci.session,
+		}
+		psi.Sync()
+
+		log.Infof("Running ProjectKeysIngestor on project %s", projectSlug)
+		pki := ProjectKeysIngestor{
+			restclient:  cci.restclient,
+			db:          cci.db,
+			data:        &ProjectKeysData{},
+			projectSlug: projectSlug.(string),
+			session:     cci.session,
+		}
+		pki.Sync()
+
+		log.Infof("Running ProjectPipelinesIngestor on project %s", projectSlug)
+		ppi := ProjectPipelinesIngestor{
+			restclient:  cci.restclient,
+			db:          cci.db,
+			data:        &ProjectPipelinesData{},
+			projectSlug: projectSlug.(string),
+			session:     cci.session,
+		}
+		ppi.Sync()
 	}
 }
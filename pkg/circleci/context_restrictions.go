@@ -0,0 +1,127 @@
+package circleci
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ovotech/gitoops/pkg/database"
+)
+
+// Ingests the security group, project and expression restrictions of a context. Contexts "All
+// members" can access are handled by the ContextsIngestor.
+type ContextRestrictionsIngestor struct {
+	restclient   *RESTClient
+	db           *database.Database
+	data         *ContextRestrictionsData
+	organization string
+	contextId    string
+	session      string
+}
+
+type ContextRestrictionsData struct {
+	Items []struct {
+		ID               string `json:"id"`
+		ProjectID        string `json:"project_id"`
+		Name             string `json:"name"`
+		RestrictionType  string `json:"restriction_type"`
+		RestrictionValue string `json:"restriction_value"`
+	} `json:"items"`
+}
+
+func (ing *ContextRestrictionsIngestor) fetchData() {
+	query := fmt.Sprintf("context/%s/restrictions", ing.contextId)
+	data := ing.restclient.fetch(query, true)
+	json.Unmarshal(data, &ing.data)
+}
+
+func (ing *ContextRestrictionsIngestor) Sync() {
+	ing.fetchData()
+	ing.insertGroupRestrictions()
+	ing.insertExpressionRestrictions()
+	ing.insertProjectRestrictions()
+}
+
+// CircleCI security groups are GitHub teams. Restrictions identify them by the team's ID, which we
+// match against the team's database ID, or by the team's slug. Both are stable across renames,
+// unlike the team's display name.
+func (ing *ContextRestrictionsIngestor) insertGroupRestrictions() {
+	groups := []string{}
+
+	for _, restriction := range ing.data.Items {
+		if restriction.RestrictionType == "group" && restriction.Name != "All members" {
+			groups = append(groups, restriction.RestrictionValue)
+		}
+	}
+
+	ing.db.Run(`
+	UNWIND $groups AS group
+
+	MATCH (c:CircleCIContext{id: $contextId})
+	MATCH (t:Team)
+	WHERE toString(t.databaseId) = group
+	OR (
+		t.slug = group
+		AND toLower(t.url) CONTAINS "/orgs/" + toLower($organization) + "/teams/"
+	)
+	MERGE (t)-[rel:HAS_ACCESS_TO_CIRCLECI_CONTEXT]->(c)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"groups":       groups,
+		"contextId":    ing.contextId,
+		"organization": ing.organization,
+		"session":      ing.session,
+	})
+}
+
+// Expressions (e.g. `pipeline.git.branch == "main"`) are evaluated by CircleCI when a job requests
+// the context, so we keep them on the context.
+func (ing *ContextRestrictionsIngestor) insertExpressionRestrictions() {
+	expressions := []string{}
+
+	for _, restriction := range ing.data.Items {
+		if restriction.RestrictionType == "expression" {
+			expressions = append(expressions, restriction.RestrictionValue)
+		}
+	}
+
+	ing.db.Run(`
+	MATCH (c:CircleCIContext{id: $contextId})
+	SET c.expressionRestrictions = $expressions
+	`, map[string]interface{}{"contextId": ing.contextId, "expressions": expressions})
+}
+
+// Contexts restricted to projects can only be used by these projects. Restrictions identify projects
+// by their ID, which is stable across renames and unique across VCS types and organizations.
+func (ing *ContextRestrictionsIngestor) insertProjectRestrictions() {
+	projects := []map[string]interface{}{}
+
+	for _, restriction := range ing.data.Items {
+		if restriction.RestrictionType != "project" {
+			continue
+		}
+		projects = append(projects, map[string]interface{}{
+			"projectId": restriction.RestrictionValue,
+		})
+	}
+
+	ing.db.Run(`
+	MATCH (c:CircleCIContext{id: $contextId})
+	SET c.projectRestricted = $projectRestricted
+	`, map[string]interface{}{
+		"contextId":         ing.contextId,
+		"projectRestricted": len(projects) > 0,
+	})
+
+	ing.db.Run(`
+	UNWIND $projects AS project
+
+	MATCH (c:CircleCIContext{id: $contextId})
+	MATCH (p:CircleCIProject{projectId: project.projectId})
+	MERGE (c)-[rel:RESTRICTED_TO]->(p)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"projects":  projects,
+		"contextId": ing.contextId,
+		"session":   ing.session,
+	})
+}
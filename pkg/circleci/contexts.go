@@ -10,6 +10,7 @@ type ContextsIngestor struct {
 	gqlclient      *GraphQLClient
 	db             *database.Database
 	data           *ContextsData
+	organization   string
 	organizationId string
 	session        string
 }
@@ -70,23 +71,25 @@ func (ing *ContextsIngestor) Sync() {
 }
 
 // Insert contexts that are scoped to teams. This basically excludes contexts that "All members" can
-// access.
+// access. Teams are linked to the contexts by the ContextRestrictionsIngestor, as only the
+// restrictions API identifies them.
 func (ing *ContextsIngestor) insertTeamsContexts() {
 	contexts := []map[string]interface{}{}
 
 	for _, contextEdge := range ing.data.Edges {
 		contextNode := contextEdge.Node
+		allMembers := false
 		for _, groupEdge := range contextNode.Groups.Edges {
-			// we ignore "All members" here, that mapping is handled in another function
-			if groupEdge.Node.Name == "All members" {
-				continue
-			}
-			contexts = append(contexts, map[string]interface{}{
-				"id":   contextNode.ID,
-				"name": contextNode.Name,
-				"team": groupEdge.Node.Name,
-			})
+			allMembers = allMembers || groupEdge.Node.Name == "All members"
+		}
+		// "All members" contexts are handled in another function
+		if allMembers {
+			continue
 		}
+		contexts = append(contexts, map[string]interface{}{
+			"id":   contextNode.ID,
+			"name": contextNode.Name,
+		})
 	}
 
 	ing.db.Run(`
@@ -95,14 +98,14 @@ func (ing *ContextsIngestor) insertTeamsContexts() {
 	MERGE (c:CircleCIContext{id: context.id})
 
 	SET c.name = context.name,
+	c.organization = $organization,
 	c.allMembers = false,
 	c.session = $session
-
-	WITH c, context
-	MATCH (t:Team{name: context.team})
-	MERGE (t)-[rel:HAS_ACCESS_TO_CIRCLECI_CONTEXT]->(c)
-	SET rel.session = $session
-	`, map[string]interface{}{"contexts": contexts, "session": ing.session})
+	`, map[string]interface{}{
+		"contexts":     contexts,
+		"organization": ing.organization,
+		"session":      ing.session,
+	})
 }
 
 // Insert contexts that are accessible by all members of the GitHub org.
@@ -129,6 +132,7 @@ func (ing *ContextsIngestor) insertAllMembersContexts() {
 	MERGE (c:CircleCIContext{id: context.id})
 
 	SET c.name = context.name,
+	c.organization = $organization,
 	c.allMembers = true,
 	c.session = $session
 
@@ -136,5 +140,9 @@ func (ing *ContextsIngestor) insertAllMembersContexts() {
 	MATCH (u:User)
 	MERGE (u)-[rel:HAS_ACCESS_TO_CIRCLECI_CONTEXT]->(c)
 	SET rel.session = $session
-	`, map[string]interface{}{"contexts": contexts, "session": ing.session})
+	`, map[string]interface{}{
+		"contexts":     contexts,
+		"organization": ing.organization,
+		"session":      ing.session,
+	})
 }
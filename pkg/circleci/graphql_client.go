@@ -21,8 +21,10 @@ var (
 )
 
 type GraphQLClient struct {
-	client *http.Client
-	cookie string
+	client  *http.Client
+	baseURL string
+	cookie  string
+	token   string
 }
 
 type GraphQLError struct {
@@ -46,17 +48,14 @@ func (c *GraphQLClient) call(query string, variables map[string]string) []byte {
 
 	req, err := http.NewRequest(
 		"POST",
-		"https://circleci.com/graphql-unstable",
+		strings.TrimSuffix(c.baseURL, "/")+"/graphql-unstable",
 		bytes.NewBuffer(jsonValue),
 	)
 	if err != nil {
 		log.Panic(err)
 	}
 
-	req.AddCookie(&http.Cookie{
-		Name:  "ring-session",
-		Value: c.cookie,
-	})
+	authenticate(req, c.cookie, c.token)
 	req.Header.Add("content-type", "application/json")
 
 	resp, err := c.client.Do(req)
@@ -14,6 +14,10 @@ type OrganizationIngestor struct {
 
 type OrganizationData struct {
 	Id string `json:"id"`
+	// the namespace of the organization's orbs and runner resource classes
+	RegistryNamespace struct {
+		Name string `json:"name"`
+	} `json:"registryNamespace"`
 }
 
 func (ing *OrganizationIngestor) fetchData() {
@@ -21,6 +25,9 @@ func (ing *OrganizationIngestor) fetchData() {
 	query Organization($vcsType: VCSType!, $orgName: String!) {
 		organization(vcsType: $vcsType, name: $orgName) {
 			id
+			registryNamespace {
+				name
+			}
 		}
 	}
 	`
@@ -40,6 +47,11 @@ func (ing *OrganizationIngestor) GetOrganizationId() string {
 	return ing.data.Id
 }
 
+// Returns the organization's namespace, once the organization ID was fetched
+func (ing *OrganizationIngestor) GetNamespace() string {
+	return ing.data.RegistryNamespace.Name
+}
+
 // func (ing *OrganizationIngestor) Sync() {
 // 	ing.fetchData()
 // 	ing.insertOrganization()
@@ -10,12 +10,11 @@ import (
 
 // Creates a relationship for a repository that has a CircleCI project.
 type ProjectEnvVarsIngestor struct {
-	restclient   *RESTClient
-	db           *database.Database
-	data         *ProjectEnvVarsData
-	organization string
-	projectName  string
-	session      string
+	restclient  *RESTClient
+	db          *database.Database
+	data        *ProjectEnvVarsData
+	projectSlug string
+	session     string
 }
 
 type ProjectEnvVarsData struct {
@@ -26,7 +25,7 @@ type ProjectEnvVarsData struct {
 }
 
 func (ing *ProjectEnvVarsIngestor) fetchData() {
-	query := fmt.Sprintf("project/gh/%s/%s/envvar", ing.organization, ing.projectName)
+	query := fmt.Sprintf("project/%s/envvar", ing.projectSlug)
 	data := ing.restclient.fetch(query, false)
 	json.Unmarshal(data, &ing.data)
 }
@@ -40,10 +39,10 @@ func (ing *ProjectEnvVarsIngestor) insertProjectEnvVars() {
 	envVars := []map[string]interface{}{}
 
 	for _, item := range ing.data.Items {
-		id := fmt.Sprintf("%x", md5.Sum([]byte(ing.projectName+item.Name)))
+		id := fmt.Sprintf("%x", md5.Sum([]byte(ing.projectSlug+item.Name)))
 		envVars = append(envVars, map[string]interface{}{
 			"id":             id,
-			"projectId":      ing.projectName,
+			"projectId":      ing.projectSlug,
 			"name":           item.Name,
 			"truncatedValue": item.Value[len(item.Value)-4:],
 		})
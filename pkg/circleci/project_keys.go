@@ -0,0 +1,95 @@
+package circleci
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ovotech/gitoops/pkg/database"
+)
+
+// Ingests a project's checkout keys, which are GitHub deploy keys or user keys, and the additional
+// SSH keys it gives jobs, e.g. to deploy to servers.
+type ProjectKeysIngestor struct {
+	restclient  *RESTClient
+	db          *database.Database
+	data        *ProjectKeysData
+	projectSlug string
+	session     string
+}
+
+type ProjectKeysData struct {
+	CheckoutKeys struct {
+		Items []struct {
+			Type        string `json:"type"`
+			Fingerprint string `json:"fingerprint"`
+			Preferred   bool   `json:"preferred"`
+		} `json:"items"`
+	}
+	Settings struct {
+		SSHKeys []struct {
+			Hostname    string `json:"hostname"`
+			Fingerprint string `json:"fingerprint"`
+		} `json:"ssh_keys"`
+	}
+}
+
+func (ing *ProjectKeysIngestor) fetchData() {
+	query := fmt.Sprintf("project/%s/checkout-key", ing.projectSlug)
+	data := ing.restclient.fetch(query, true)
+	json.Unmarshal(data, &ing.data.CheckoutKeys)
+
+	// additional SSH keys are only available from the v1.1 API
+	query = fmt.Sprintf("project/%s/settings", ing.projectSlug)
+	data = ing.restclient.fetchV1(query)
+	json.Unmarshal(data, &ing.data.Settings)
+}
+
+func (ing *ProjectKeysIngestor) Sync() {
+	ing.fetchData()
+	ing.insertProjectKeys()
+}
+
+func (ing *ProjectKeysIngestor) insertProjectKeys() {
+	keys := []map[string]interface{}{}
+
+	for _, key := range ing.data.CheckoutKeys.Items {
+		keys = append(keys, map[string]interface{}{
+			"fingerprint": key.Fingerprint,
+			"type":        key.Type,
+			"preferred":   key.Preferred,
+			"hostname":    "github.com",
+		})
+	}
+	for _, key := range ing.data.Settings.SSHKeys {
+		keys = append(keys, map[string]interface{}{
+			"fingerprint": key.Fingerprint,
+			"type":        "additional-ssh-key",
+			"preferred":   false,
+			"hostname":    key.Hostname,
+		})
+	}
+
+	// nb: SSH keys are identified by their MD5 fingerprint, which is also how we ingest GitHub
+	// deploy keys and user keys
+	ing.db.Run(`
+	UNWIND $keys AS key
+
+	MERGE (k:SSHKey{id: key.fingerprint})
+
+	SET k.fingerprint = key.fingerprint,
+	k.session = $session
+
+	WITH k, key
+
+	MATCH (p:CircleCIProject{id: $projectSlug})
+	MERGE (p)-[rel:HAS_KEY]->(k)
+	SET rel.type = key.type,
+	rel.preferred = key.preferred,
+	rel.hostname = key.hostname,
+	rel.session = $session
+	`, map[string]interface{}{
+		"keys":        keys,
+		"projectSlug": ing.projectSlug,
+		"session":     ing.session,
+	})
+}
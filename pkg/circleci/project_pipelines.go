@@ -0,0 +1,145 @@
+package circleci
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ovotech/gitoops/pkg/database"
+)
+
+// Ingests metadata of a project's recent pipelines: when it last ran and who triggered pipelines.
+type ProjectPipelinesIngestor struct {
+	restclient  *RESTClient
+	db          *database.Database
+	data        *ProjectPipelinesData
+	projectSlug string
+	session     string
+}
+
+type ProjectPipelinesData struct {
+	Items []struct {
+		ID          string        `json:"id"`
+		Errors      []interface{} `json:"errors"`
+		ProjectSlug string        `json:"project_slug"`
+		UpdatedAt   time.Time     `json:"updated_at"`
+		Number      int           `json:"number"`
+		State       string        `json:"state"`
+		CreatedAt   time.Time     `json:"created_at"`
+		Trigger     struct {
+			ReceivedAt time.Time `json:"received_at"`
+			Type       string    `json:"type"`
+			Actor      struct {
+				Login     string `json:"login"`
+				AvatarURL string `json:"avatar_url"`
+			} `json:"actor"`
+		} `json:"trigger"`
+		Vcs struct {
+			OriginRepositoryURL string `json:"origin_repository_url"`
+			TargetRepositoryURL string `json:"target_repository_url"`
+			Revision            string `json:"revision"`
+			ProviderName        string `json:"provider_name"`
+			Commit              struct {
+				Body    string `json:"body"`
+				Subject string `json:"subject"`
+			} `json:"commit"`
+			Branch string `json:"branch"`
+		} `json:"vcs"`
+	} `json:"items"`
+}
+
+func (ing *ProjectPipelinesIngestor) fetchData() {
+	// we only look at the first page of pipelines, the most recent ones
+	query := fmt.Sprintf("project/%s/pipeline", ing.projectSlug)
+	data := ing.restclient.fetch(query, false)
+	json.Unmarshal(data, &ing.data)
+}
+
+func (ing *ProjectPipelinesIngestor) Sync() {
+	ing.fetchData()
+	ing.insertLastPipeline()
+	ing.insertPipelinesTriggers()
+}
+
+func (ing *ProjectPipelinesIngestor) insertLastPipeline() {
+	if len(ing.data.Items) < 1 {
+		return
+	}
+	// pipelines are sorted from most recent
+	pipeline := ing.data.Items[0]
+
+	ing.db.Run(`
+	MATCH (p:CircleCIProject{id: $projectSlug})
+	SET p.lastPipelineAt = datetime($createdAt),
+	p.lastPipelineBranch = $branch,
+	p.lastPipelineTriggerType = $triggerType
+	`, map[string]interface{}{
+		"projectSlug": ing.projectSlug,
+		"createdAt":   pipeline.CreatedAt.Format(time.RFC3339),
+		"branch":      pipeline.Vcs.Branch,
+		"triggerType": pipeline.Trigger.Type,
+	})
+}
+
+// Links users to the projects they triggered recent pipelines on. Counts are only of the pipelines
+// we fetched, i.e. the first page of the project's pipelines, see docs/schema.md.
+func (ing *ProjectPipelinesIngestor) insertPipelinesTriggers() {
+	type actorPipelines struct {
+		count        int
+		lastAt       time.Time
+		triggerTypes []string
+	}
+	actors := map[string]*actorPipelines{}
+
+	for _, pipeline := range ing.data.Items {
+		login := pipeline.Trigger.Actor.Login
+		if login == "" {
+			continue
+		}
+		if _, ok := actors[login]; !ok {
+			actors[login] = &actorPipelines{triggerTypes: []string{}}
+		}
+		actor := actors[login]
+		actor.count++
+		if pipeline.CreatedAt.After(actor.lastAt) {
+			actor.lastAt = pipeline.CreatedAt
+		}
+		actor.triggerTypes = appendUnique(actor.triggerTypes, pipeline.Trigger.Type)
+	}
+
+	triggers := []map[string]interface{}{}
+	for login, actor := range actors {
+		triggers = append(triggers, map[string]interface{}{
+			"login":        login,
+			"count":        actor.count,
+			"lastAt":       actor.lastAt.Format(time.RFC3339),
+			"triggerTypes": actor.triggerTypes,
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $triggers AS trigger
+
+	MATCH (p:CircleCIProject{id: $projectSlug})
+	MATCH (u:User{login: trigger.login})
+	MERGE (u)-[rel:TRIGGERED_PIPELINE]->(p)
+	SET rel.count = trigger.count,
+	rel.lastAt = datetime(trigger.lastAt),
+	rel.triggerTypes = trigger.triggerTypes,
+	rel.session = $session
+	`, map[string]interface{}{
+		"triggers":    triggers,
+		"projectSlug": ing.projectSlug,
+		"session":     ing.session,
+	})
+}
+
+// Appends value to a slice unless it's already in there.
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,81 @@
+package circleci
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ovotech/gitoops/pkg/database"
+)
+
+// Ingests the advanced settings of a project, most importantly whether it builds pull requests from
+// forks and passes them its secrets.
+type ProjectSettingsIngestor struct {
+	restclient  *RESTClient
+	db          *database.Database
+	data        *ProjectSettingsData
+	projectSlug string
+	session     string
+}
+
+type ProjectSettingsData struct {
+	Advanced struct {
+		BuildForkPRs              bool `json:"build_fork_prs"`
+		ForksReceiveSecretEnvVars bool `json:"forks_receive_secret_env_vars"`
+		OSS                       bool `json:"oss"`
+		BuildPRsOnly              bool `json:"build_prs_only"`
+		SetupWorkflows            bool `json:"setup_workflows"`
+	} `json:"advanced"`
+}
+
+func (ing *ProjectSettingsIngestor) fetchData() {
+	query := fmt.Sprintf("project/%s/settings", ing.projectSlug)
+	data := ing.restclient.fetch(query, false)
+	json.Unmarshal(data, &ing.data)
+}
+
+func (ing *ProjectSettingsIngestor) Sync() {
+	ing.fetchData()
+	ing.insertProjectSettings()
+}
+
+func (ing *ProjectSettingsIngestor) insertProjectSettings() {
+	settings := ing.data.Advanced
+
+	ing.db.Run(`
+	MATCH (p:CircleCIProject{id: $projectSlug})
+
+	SET p.buildForkPRs = $buildForkPRs,
+	p.forksReceiveSecretEnvVars = $forksReceiveSecretEnvVars,
+	p.oss = $oss,
+	p.buildPRsOnly = $buildPRsOnly,
+	p.setupWorkflows = $setupWorkflows
+
+	WITH p
+
+	OPTIONAL MATCH (:Anyone)-[rel:CAN_RUN_CODE_WITH_SECRETS]->(p)
+	DELETE rel
+	`, map[string]interface{}{
+		"projectSlug":               ing.projectSlug,
+		"buildForkPRs":              settings.BuildForkPRs,
+		"forksReceiveSecretEnvVars": settings.ForksReceiveSecretEnvVars,
+		"oss":                       settings.OSS,
+		"buildPRsOnly":              settings.BuildPRsOnly,
+		"setupWorkflows":            settings.SetupWorkflows,
+	})
+
+	// anyone able to fork the repository can open a pull request running their code with the
+	// project's secrets
+	if !settings.BuildForkPRs || !settings.ForksReceiveSecretEnvVars {
+		return
+	}
+
+	ing.db.Run(`
+	MERGE (a:Anyone{id: "anyone"})
+
+	WITH a
+
+	MATCH (p:CircleCIProject{id: $projectSlug})
+	MERGE (a)-[rel:CAN_RUN_CODE_WITH_SECRETS]->(p)
+	SET rel.session = $session
+	`, map[string]interface{}{"projectSlug": ing.projectSlug, "session": ing.session})
+}
@@ -3,78 +3,117 @@ package circleci
 import (
 	"encoding/json"
 	"fmt"
-	"time"
+	"strings"
 
 	"github.com/ovotech/gitoops/pkg/database"
 )
 
-// Creates a relationship for a repository that has a CircleCI project.
-type ProjectIngestor struct {
-	restclient   *RESTClient
-	db           *database.Database
-	data         *ProjectData
-	organization string
-	repoName     string
-	session      string
+// Maps the VCS types of the v1.1 API to the prefixes of project slugs.
+var vcsSlugPrefixes = map[string]string{
+	"github":    "gh",
+	"bitbucket": "bb",
+	"circleci":  "circleci",
 }
 
-type ProjectData struct {
-	Items []struct {
-		ID          string        `json:"id"`
-		Errors      []interface{} `json:"errors"`
-		ProjectSlug string        `json:"project_slug"`
-		UpdatedAt   time.Time     `json:"updated_at"`
-		Number      int           `json:"number"`
-		State       string        `json:"state"`
-		CreatedAt   time.Time     `json:"created_at"`
-		Trigger     struct {
-			ReceivedAt time.Time `json:"received_at"`
-			Type       string    `json:"type"`
-			Actor      struct {
-				Login     string `json:"login"`
-				AvatarURL string `json:"avatar_url"`
-			} `json:"actor"`
-		} `json:"trigger"`
-		Vcs struct {
-			OriginRepositoryURL string `json:"origin_repository_url"`
-			TargetRepositoryURL string `json:"target_repository_url"`
-			Revision            string `json:"revision"`
-			ProviderName        string `json:"provider_name"`
-			Commit              struct {
-				Body    string `json:"body"`
-				Subject string `json:"subject"`
-			} `json:"commit"`
-			Branch string `json:"branch"`
-		} `json:"vcs"`
-	} `json:"items"`
+// Discovers the organization's projects, i.e. the projects followed by the authenticated user, and
+// links them to their repository.
+type ProjectsIngestor struct {
+	restclient     *RESTClient
+	db             *database.Database
+	data           *ProjectsData
+	organization   string
+	organizationId string
+	session        string
 }
 
-func (ing *ProjectIngestor) fetchData() {
-	query := fmt.Sprintf("project/gh/%s/%s/pipeline", ing.organization, ing.repoName)
-	data := ing.restclient.fetch(query, false)
+type ProjectsData []struct {
+	Username string `json:"username"`
+	Reponame string `json:"reponame"`
+	VCSType  string `json:"vcs_type"`
+	VCSURL   string `json:"vcs_url"`
+	// the project's ID, which only the v2 API returns
+	ID string `json:"-"`
+}
+
+type projectDetails struct {
+	ID string `json:"id"`
+}
+
+func (ing *ProjectsIngestor) fetchData() {
+	// the v2 API doesn't have a way to list projects
+	data := ing.restclient.fetchV1("projects")
 	json.Unmarshal(data, &ing.data)
+
+	// context restrictions refer to projects by their ID
+	for i, project := range *ing.data {
+		slug := ing.projectSlug(project.Username, project.Reponame, project.VCSType)
+		if slug == "" {
+			continue
+		}
+		details := projectDetails{}
+		json.Unmarshal(ing.restclient.fetch("project/"+slug, false), &details)
+		(*ing.data)[i].ID = details.ID
+	}
 }
 
-func (ing *ProjectIngestor) Sync() {
+func (ing *ProjectsIngestor) Sync() {
 	ing.fetchData()
-	ing.insertProject()
+	ing.insertProjects()
 }
 
-func (ing *ProjectIngestor) insertProject() {
-	if len(ing.data.Items) < 1 {
-		return
+// Returns the slug of a project of the organization, or an empty string for other projects.
+func (ing *ProjectsIngestor) projectSlug(username, reponame, vcsType string) string {
+	// standalone projects' usernames are organization IDs
+	if !strings.EqualFold(username, ing.organization) && username != ing.organizationId {
+		return ""
+	}
+	prefix, ok := vcsSlugPrefixes[vcsType]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/%s", prefix, username, reponame)
+}
+
+func (ing *ProjectsIngestor) insertProjects() {
+	projects := []map[string]interface{}{}
+
+	for _, project := range *ing.data {
+		slug := ing.projectSlug(project.Username, project.Reponame, project.VCSType)
+		if slug == "" {
+			continue
+		}
+
+		projects = append(projects, map[string]interface{}{
+			"slug":      slug,
+			"projectId": project.ID,
+			"name":      project.Reponame,
+			"vcsType":   project.VCSType,
+			"vcsURL":    project.VCSURL,
+		})
 	}
 
 	ing.db.Run(`
-	MERGE (p:CircleCIProject{id: $repoName})
+	UNWIND $projects AS project
+
+	MERGE (p:CircleCIProject{id: project.slug})
 
-	SET p.repository = $repoName,
+	SET p.slug = project.slug,
+	p.projectId = project.projectId,
+	p.name = project.name,
+	p.repository = project.name,
+	p.organization = $organization,
+	p.vcsType = project.vcsType,
+	p.vcsURL = project.vcsURL,
 	p.session = $session
 
-	WITH p, $repoName as repoName
+	WITH p, project
 
-	MATCH (r:Repository{name: $repoName})
+	MATCH (r:Repository{id: project.vcsURL})
 	MERGE (r)-[rel:HAS_CI]->(p)
 	SET rel.session = $session
-	`, map[string]interface{}{"repoName": ing.repoName, "session": ing.session})
+	`, map[string]interface{}{
+		"projects":     projects,
+		"organization": ing.organization,
+		"session":      ing.session,
+	})
 }
@@ -11,8 +11,12 @@ import (
 )
 
 type RESTClient struct {
-	client *http.Client
-	cookie string
+	client  *http.Client
+	baseURL string
+	// the runner API is hosted separately on CircleCI cloud
+	runnerURL string
+	cookie    string
+	token     string
 }
 
 type RESTError struct {
@@ -21,12 +25,14 @@ type RESTError struct {
 	count   int
 }
 
-// Retrieves a single page for a REST query.
-func (c *RESTClient) call(resourcePath, pageToken string) (int, []byte) {
+// Retrieves a single page for a REST query, resourcePath being relative to the apiURL.
+func (c *RESTClient) call(apiURL, resourcePath, pageToken string) (int, []byte) {
 	log.Debugf("Issuing REST query for path %s", resourcePath)
 
-	u, _ := url.Parse("https://circleci.com/api/v2/")
-	u.Path = path.Join(u.Path, resourcePath)
+	u, _ := url.Parse(apiURL)
+	resource, _ := url.Parse(resourcePath)
+	u.Path = path.Join(u.Path, resource.Path)
+	u.RawQuery = resource.RawQuery
 
 	req, err := http.NewRequest(
 		"GET",
@@ -37,10 +43,7 @@ func (c *RESTClient) call(resourcePath, pageToken string) (int, []byte) {
 		panic(err)
 	}
 
-	req.AddCookie(&http.Cookie{
-		Name:  "ring-session",
-		Value: c.cookie,
-	})
+	authenticate(req, c.cookie, c.token)
 	req.Header.Add("accept", "application/json")
 
 	if pageToken != "" {
@@ -62,6 +65,18 @@ func (c *RESTClient) call(resourcePath, pageToken string) (int, []byte) {
 	return resp.StatusCode, body
 }
 
+// Authenticates a request with a personal API token if we have one, or the browser session cookie.
+func authenticate(req *http.Request, cookie, token string) {
+	if token != "" {
+		req.Header.Add("Circle-Token", token)
+		return
+	}
+	req.AddCookie(&http.Cookie{
+		Name:  "ring-session",
+		Value: cookie,
+	})
+}
+
 // Retrieves all pages for a REST URL path.
 func (c *RESTClient) fetch(resourcePath string, all bool) []byte {
 	data := gabs.New()
@@ -70,7 +85,7 @@ func (c *RESTClient) fetch(resourcePath string, all bool) []byte {
 	// map status code to RESTError
 	errorTracker := map[int]RESTError{}
 	for {
-		code, resp := c.call(resourcePath, pageToken)
+		code, resp := c.call(c.baseURL+"/api/v2", resourcePath, pageToken)
 
 		parsedResp, err := gabs.ParseJSON(resp)
 		if err != nil {
@@ -98,6 +113,34 @@ func (c *RESTClient) fetch(resourcePath string, all bool) []byte {
 	return data.Bytes()
 }
 
+// Retrieves a REST URL path from the v1.1 API, for the few things the v2 API doesn't expose.
+func (c *RESTClient) fetchV1(resourcePath string) []byte {
+	return c.fetchPage(c.baseURL+"/api/v1.1", resourcePath)
+}
+
+// Retrieves a REST URL path from the runner API.
+func (c *RESTClient) fetchRunner(resourcePath string) []byte {
+	return c.fetchPage(c.runnerURL+"/api/v3/runner", resourcePath)
+}
+
+// Retrieves a single page for a REST URL path of an API that isn't paginated like the v2 API.
+func (c *RESTClient) fetchPage(apiURL, resourcePath string) []byte {
+	errorTracker := map[int]RESTError{}
+
+	code, resp := c.call(apiURL, resourcePath, "")
+	parsedResp, err := gabs.ParseJSON(resp)
+	if err != nil {
+		panic(err)
+	}
+	if code != 200 {
+		c.trackFetchErrors(code, parsedResp, errorTracker)
+	}
+
+	c.logFetchErrors(resourcePath, errorTracker)
+
+	return parsedResp.Bytes()
+}
+
 func (c *RESTClient) trackFetchErrors(
 	code int,
 	parsedResp *gabs.Container,
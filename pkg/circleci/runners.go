@@ -0,0 +1,69 @@
+package circleci
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ovotech/gitoops/pkg/database"
+)
+
+// Ingests the organization's self-hosted runner resource classes. Jobs running on these run inside
+// our network, and the enricher links the projects whose jobs use them.
+type RunnersIngestor struct {
+	restclient *RESTClient
+	db         *database.Database
+	data       *RunnersData
+	namespace  string
+	session    string
+}
+
+type RunnersData struct {
+	Items []struct {
+		ID            string `json:"id"`
+		ResourceClass string `json:"resource_class"`
+		Description   string `json:"description"`
+	} `json:"items"`
+}
+
+func (ing *RunnersIngestor) fetchData() {
+	query := fmt.Sprintf("resource?namespace=%s", url.QueryEscape(ing.namespace))
+	data := ing.restclient.fetchRunner(query)
+	json.Unmarshal(data, &ing.data)
+}
+
+func (ing *RunnersIngestor) Sync() {
+	ing.fetchData()
+	ing.insertRunners()
+}
+
+func (ing *RunnersIngestor) insertRunners() {
+	runners := []map[string]interface{}{}
+
+	for _, resourceClass := range ing.data.Items {
+		runners = append(runners, map[string]interface{}{
+			"resourceClass": resourceClass.ResourceClass,
+			"name":          strings.TrimPrefix(resourceClass.ResourceClass, ing.namespace+"/"),
+			"description":   resourceClass.Description,
+		})
+	}
+
+	// nb: resource classes are unique as namespaces are
+	ing.db.Run(`
+	UNWIND $runners AS runner
+
+	MERGE (r:Runner{id: runner.resourceClass})
+
+	SET r.resourceClass = runner.resourceClass,
+	r.namespace = $namespace,
+	r.name = runner.name,
+	r.description = runner.description,
+	r.platform = "circleci",
+	r.session = $session
+	`, map[string]interface{}{
+		"runners":   runners,
+		"namespace": ing.namespace,
+		"session":   ing.session,
+	})
+}
@@ -0,0 +1,212 @@
+package cloudci
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/ovotech/gitoops/internal/utils"
+	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
+)
+
+// Ingests Google Cloud Build triggers building GitHub repositories and the service accounts they run
+// as.
+type CloudBuildTriggersIngestor struct {
+	db           *database.Database
+	data         *CloudBuildTriggersData
+	path         string
+	organization string
+	session      string
+}
+
+type CloudBuildTriggersData []CloudBuildTrigger
+
+type CloudBuildTrigger struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	ResourceName string `json:"resourceName"`
+	Disabled     bool   `json:"disabled"`
+	GitHub       *struct {
+		Owner       string                       `json:"owner"`
+		Name        string                       `json:"name"`
+		PullRequest *CloudBuildPullRequestFilter `json:"pullRequest"`
+	} `json:"github"`
+	SourceToBuild *struct {
+		URI string `json:"uri"`
+	} `json:"sourceToBuild"`
+	// set by 2nd-gen triggers, whose repository is a resource of a connection to GitHub
+	RepositoryEventConfig *struct {
+		Repository  string                       `json:"repository"`
+		PullRequest *CloudBuildPullRequestFilter `json:"pullRequest"`
+	} `json:"repositoryEventConfig"`
+	ServiceAccount string `json:"serviceAccount"`
+}
+
+type CloudBuildPullRequestFilter struct {
+	Branch         string `json:"branch"`
+	CommentControl string `json:"commentControl"`
+}
+
+func (ing *CloudBuildTriggersIngestor) fetchData() {
+	data, err := os.ReadFile(ing.path)
+	if err != nil {
+		log.Fatalf("Error reading Cloud Build triggers: %s", err)
+	}
+	if err := json.Unmarshal(data, &ing.data); err != nil {
+		log.Fatalf("Error parsing Cloud Build triggers: %s", err)
+	}
+}
+
+func (ing *CloudBuildTriggersIngestor) Sync() {
+	ing.fetchData()
+	ing.insertTriggers()
+}
+
+func (ing *CloudBuildTriggersIngestor) insertTriggers() {
+	triggers := []map[string]interface{}{}
+
+	for _, trigger := range *ing.data {
+		pullRequest := triggerPullRequestFilter(trigger)
+		commentControl := ""
+		if pullRequest != nil {
+			commentControl = pullRequest.CommentControl
+		}
+
+		// service accounts are given as projects/PROJECT/serviceAccounts/EMAIL, and triggers without
+		// one run as the project's default Cloud Build service account
+		serviceAccount, account := "", ""
+		if parts := strings.Split(trigger.ServiceAccount, "/"); len(parts) == 4 {
+			serviceAccount, account = parts[3], parts[1]
+			// the project of user-managed service accounts is in their email
+			if domain := strings.SplitN(serviceAccount, "@", 2); len(domain) == 2 &&
+				strings.HasSuffix(domain[1], ".iam.gserviceaccount.com") {
+				account = strings.TrimSuffix(domain[1], ".iam.gserviceaccount.com")
+			}
+		}
+
+		triggers = append(triggers, map[string]interface{}{
+			"id":                          trigger.ID,
+			"name":                        trigger.Name,
+			"resourceName":                trigger.ResourceName,
+			"disabled":                    trigger.Disabled,
+			"repositoryPaths":             triggerRepositoryPaths(trigger, ing.organization),
+			"serviceAccount":              serviceAccount,
+			"account":                     account,
+			"buildsPullRequests":          pullRequest != nil,
+			"commentControl":              commentControl,
+			"buildsUntrustedPullRequests": buildsUntrustedPullRequests(trigger),
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $triggers AS trigger
+
+	MERGE (t:CloudBuildTrigger{id: trigger.id})
+
+	SET t.name = trigger.name,
+	t.resourceName = trigger.resourceName,
+	t.disabled = trigger.disabled,
+	t.buildsPullRequests = trigger.buildsPullRequests,
+	t.commentControl = trigger.commentControl,
+	t.buildsUntrustedPullRequests = trigger.buildsUntrustedPullRequests,
+	t.usesDefaultServiceAccount = (trigger.serviceAccount = ""),
+	t.session = $session
+
+	WITH t, trigger
+
+	OPTIONAL MATCH (t)-[rel:RUNS_AS]->()
+	DELETE rel
+
+	WITH t, trigger
+	WHERE trigger.serviceAccount <> ""
+
+	MERGE (c:CloudIdentity{id: "gcp:" + trigger.serviceAccount})
+	SET c.provider = "gcp",
+	c.type = "serviceAccount",
+	c.identifier = trigger.serviceAccount,
+	c.account = trigger.account,
+	c.session = $session
+
+	MERGE (t)-[rel:RUNS_AS]->(c)
+	SET rel.session = $session
+	`, map[string]interface{}{"triggers": triggers, "session": ing.session})
+
+	ing.db.Run(`
+	UNWIND $triggers AS trigger
+
+	MATCH (t:CloudBuildTrigger{id: trigger.id})
+	OPTIONAL MATCH (:Anyone)-[rel:CAN_RUN_CODE_WITH_SECRETS]->(t)
+	DELETE rel
+
+	WITH t, trigger
+	WHERE trigger.buildsUntrustedPullRequests
+
+	MERGE (a:Anyone{id: "anyone"})
+	MERGE (a)-[rel:CAN_RUN_CODE_WITH_SECRETS]->(t)
+	SET rel.session = $session
+	`, map[string]interface{}{"triggers": triggers, "session": ing.session})
+
+	ing.db.Run(`
+	UNWIND $triggers AS trigger
+	UNWIND trigger.repositoryPaths AS repositoryPath
+
+	MATCH (t:CloudBuildTrigger{id: trigger.id})
+	MATCH (r:Repository)-[:OWNED_BY]->(:Organization{login: $organization})
+	WHERE toLower(r.id) ENDS WITH "/" + toLower(repositoryPath)
+	MERGE (r)-[rel:HAS_CI]->(t)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"triggers":     triggers,
+		"organization": ing.organization,
+		"session":      ing.session,
+	})
+}
+
+// Returns the "owner/name" paths of the repositories a trigger builds, matched against the end of
+// GitHub repositories' URLs. The repositories of 2nd-gen triggers are given as
+// projects/PROJECT/locations/LOCATION/connections/CONNECTION/repositories/REPOSITORY, whose name
+// is the GitHub repository's name or, when linked from the console, OWNER-NAME. As connections
+// link a single GitHub organization, we match those on the name alone, trying both.
+func triggerRepositoryPaths(trigger CloudBuildTrigger, organization string) []string {
+	repositoryPaths := []string{}
+	if trigger.GitHub != nil {
+		repositoryPaths = append(repositoryPaths, trigger.GitHub.Owner+"/"+trigger.GitHub.Name)
+	}
+	if trigger.SourceToBuild != nil && trigger.SourceToBuild.URI != "" {
+		repositoryPaths = append(repositoryPaths, utils.RepositoryPath(trigger.SourceToBuild.URI))
+	}
+	if trigger.RepositoryEventConfig != nil {
+		parts := strings.Split(trigger.RepositoryEventConfig.Repository, "/")
+		if len(parts) == 8 && parts[6] == "repositories" {
+			name := parts[7]
+			repositoryPaths = append(repositoryPaths, name)
+			if prefix := strings.ToLower(organization) + "-"; strings.HasPrefix(strings.ToLower(name), prefix) {
+				repositoryPaths = append(repositoryPaths, name[len(prefix):])
+			}
+		}
+	}
+	return utils.RemoveDuplicateStrings(repositoryPaths)
+}
+
+// Returns the pull request filter of a trigger, nil unless it builds pull requests.
+func triggerPullRequestFilter(trigger CloudBuildTrigger) *CloudBuildPullRequestFilter {
+	if trigger.RepositoryEventConfig != nil && trigger.RepositoryEventConfig.PullRequest != nil {
+		return trigger.RepositoryEventConfig.PullRequest
+	}
+	if trigger.GitHub != nil {
+		return trigger.GitHub.PullRequest
+	}
+	return nil
+}
+
+// Returns whether a trigger builds pull requests from forks. Unless comment control is enabled, which
+// requires an owner or collaborator to comment `/gcbrun` (for all pull requests or only those from
+// external contributors), pull requests from forks run the build as the trigger's service account.
+func buildsUntrustedPullRequests(trigger CloudBuildTrigger) bool {
+	pullRequest := triggerPullRequestFilter(trigger)
+	if trigger.Disabled || pullRequest == nil {
+		return false
+	}
+	return pullRequest.CommentControl == "" || pullRequest.CommentControl == "COMMENTS_DISABLED"
+}
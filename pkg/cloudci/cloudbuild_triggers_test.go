@@ -0,0 +1,126 @@
+package cloudci
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func parseTrigger(t *testing.T, data string) CloudBuildTrigger {
+	trigger := CloudBuildTrigger{}
+	if err := json.Unmarshal([]byte(data), &trigger); err != nil {
+		t.Fatal(err)
+	}
+	return trigger
+}
+
+func TestBuildsUntrustedPullRequests(t *testing.T) {
+	testCases := []struct {
+		name    string
+		trigger string
+		want    bool
+	}{
+		{
+			name:    "push trigger",
+			trigger: `{"github": {"owner": "fakenews", "name": "api", "push": {"branch": "^main$"}}}`,
+			want:    false,
+		},
+		{
+			name:    "pull requests without comment control",
+			trigger: `{"github": {"owner": "fakenews", "name": "api", "pullRequest": {"branch": ".*"}}}`,
+			want:    true,
+		},
+		{
+			name: "pull requests with comment control disabled",
+			trigger: `{"github": {"owner": "fakenews", "name": "api",
+				"pullRequest": {"branch": ".*", "commentControl": "COMMENTS_DISABLED"}}}`,
+			want: true,
+		},
+		{
+			name: "pull requests with comment control",
+			trigger: `{"github": {"owner": "fakenews", "name": "api",
+				"pullRequest": {"branch": ".*", "commentControl": "COMMENTS_ENABLED"}}}`,
+			want: false,
+		},
+		{
+			name: "pull requests with comment control for external contributors",
+			trigger: `{"github": {"owner": "fakenews", "name": "api",
+				"pullRequest": {"branch": ".*", "commentControl": "COMMENTS_ENABLED_FOR_EXTERNAL_CONTRIBUTORS_ONLY"}}}`,
+			want: false,
+		},
+		{
+			name: "disabled trigger",
+			trigger: `{"disabled": true, "github": {"owner": "fakenews", "name": "api",
+				"pullRequest": {"branch": ".*"}}}`,
+			want: false,
+		},
+		{
+			name: "2nd-gen pull requests",
+			trigger: `{"repositoryEventConfig": {
+				"repository": "projects/p/locations/europe-west1/connections/github/repositories/api",
+				"pullRequest": {"branch": ".*"}}}`,
+			want: true,
+		},
+		{
+			name: "2nd-gen pull requests with comment control",
+			trigger: `{"repositoryEventConfig": {
+				"repository": "projects/p/locations/europe-west1/connections/github/repositories/api",
+				"pullRequest": {"branch": ".*", "commentControl": "COMMENTS_ENABLED"}}}`,
+			want: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := buildsUntrustedPullRequests(parseTrigger(t, tc.trigger)); got != tc.want {
+				t.Errorf("buildsUntrustedPullRequests() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTriggerRepositoryPaths(t *testing.T) {
+	testCases := []struct {
+		name    string
+		trigger string
+		want    []string
+	}{
+		{
+			name:    "GitHub app trigger",
+			trigger: `{"github": {"owner": "fakenews", "name": "api"}}`,
+			want:    []string{"fakenews/api"},
+		},
+		{
+			name: "manual trigger",
+			trigger: `{"github": {"owner": "fakenews", "name": "api"},
+				"sourceToBuild": {"uri": "https://github.com/fakenews/api.git", "ref": "refs/heads/main"}}`,
+			want: []string{"fakenews/api"},
+		},
+		{
+			name: "2nd-gen trigger",
+			trigger: `{"repositoryEventConfig": {
+				"repository": "projects/p/locations/europe-west1/connections/github/repositories/api"}}`,
+			want: []string{"api"},
+		},
+		{
+			name: "2nd-gen trigger of a repository linked from the console",
+			trigger: `{"repositoryEventConfig": {
+				"repository": "projects/p/locations/europe-west1/connections/github/repositories/FakeNews-api"}}`,
+			want: []string{"FakeNews-api", "api"},
+		},
+		{
+			name:    "2nd-gen trigger without a repository",
+			trigger: `{"repositoryEventConfig": {"repository": ""}}`,
+			want:    []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := triggerRepositoryPaths(parseTrigger(t, tc.trigger), "fakenews")
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("triggerRepositoryPaths() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
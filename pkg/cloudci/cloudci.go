@@ -0,0 +1,58 @@
+package cloudci
+
+import (
+	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
+)
+
+// Ingests server-side CI/CD systems configured in cloud providers, which don't show in
+// repositories, from exported definitions so we don't need cloud credentials.
+type CloudCI struct {
+	db                     *database.Database
+	organization           string
+	codeBuildProjectsPath  string
+	cloudBuildTriggersPath string
+	session                string
+}
+
+// Returns a CloudCI ingestor reading CodeBuild projects exported with
+// `aws codebuild batch-get-projects` and Cloud Build triggers exported with
+// `gcloud builds triggers list --format=json`. Either path may be empty.
+func GetCloudCI(
+	db *database.Database,
+	organization, codeBuildProjectsPath, cloudBuildTriggersPath, session string,
+) *CloudCI {
+	return &CloudCI{
+		db:                     db,
+		organization:           organization,
+		codeBuildProjectsPath:  codeBuildProjectsPath,
+		cloudBuildTriggersPath: cloudBuildTriggersPath,
+		session:                session,
+	}
+}
+
+func (c *CloudCI) Sync() {
+	if c.codeBuildProjectsPath != "" {
+		log.Info("Running CodeBuildProjectsIngestor")
+		cbpi := CodeBuildProjectsIngestor{
+			db:           c.db,
+			data:         &CodeBuildProjectsData{},
+			path:         c.codeBuildProjectsPath,
+			organization: c.organization,
+			session:      c.session,
+		}
+		cbpi.Sync()
+	}
+
+	if c.cloudBuildTriggersPath != "" {
+		log.Info("Running CloudBuildTriggersIngestor")
+		cbti := CloudBuildTriggersIngestor{
+			db:           c.db,
+			data:         &CloudBuildTriggersData{},
+			path:         c.cloudBuildTriggersPath,
+			organization: c.organization,
+			session:      c.session,
+		}
+		cbti.Sync()
+	}
+}
@@ -0,0 +1,183 @@
+package cloudci
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/ovotech/gitoops/internal/utils"
+	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
+)
+
+// Ingests AWS CodeBuild projects building GitHub repositories and the IAM roles they run as.
+type CodeBuildProjectsIngestor struct {
+	db           *database.Database
+	data         *CodeBuildProjectsData
+	path         string
+	organization string
+	session      string
+}
+
+type CodeBuildProjectsData struct {
+	Projects []struct {
+		Name             string            `json:"name"`
+		ARN              string            `json:"arn"`
+		Source           CodeBuildSource   `json:"source"`
+		SecondarySources []CodeBuildSource `json:"secondarySources"`
+		ServiceRole      string            `json:"serviceRole"`
+		Webhook          *struct {
+			FilterGroups [][]CodeBuildWebhookFilter `json:"filterGroups"`
+		} `json:"webhook"`
+	} `json:"projects"`
+}
+
+type CodeBuildWebhookFilter struct {
+	Type                  string `json:"type"`
+	Pattern               string `json:"pattern"`
+	ExcludeMatchedPattern bool   `json:"excludeMatchedPattern"`
+}
+
+type CodeBuildSource struct {
+	Type     string `json:"type"`
+	Location string `json:"location"`
+}
+
+func (ing *CodeBuildProjectsIngestor) fetchData() {
+	data, err := os.ReadFile(ing.path)
+	if err != nil {
+		log.Fatalf("Error reading CodeBuild projects: %s", err)
+	}
+	if err := json.Unmarshal(data, &ing.data); err != nil {
+		log.Fatalf("Error parsing CodeBuild projects: %s", err)
+	}
+}
+
+func (ing *CodeBuildProjectsIngestor) Sync() {
+	ing.fetchData()
+	ing.insertProjects()
+}
+
+func (ing *CodeBuildProjectsIngestor) insertProjects() {
+	projects := []map[string]interface{}{}
+
+	for _, project := range ing.data.Projects {
+		repositoryPaths := []string{}
+		sources := append([]CodeBuildSource{project.Source}, project.SecondarySources...)
+		for _, source := range sources {
+			if strings.HasPrefix(source.Type, "GITHUB") {
+				repositoryPaths = append(repositoryPaths, utils.RepositoryPath(source.Location))
+			}
+		}
+
+		buildsPullRequests, buildsUntrustedPullRequests := false, false
+		if project.Webhook != nil {
+			buildsPullRequests, buildsUntrustedPullRequests = webhookPullRequests(project.Webhook.FilterGroups)
+		}
+
+		account := ""
+		if parts := strings.Split(project.ServiceRole, ":"); len(parts) > 4 {
+			account = parts[4]
+		}
+
+		projects = append(projects, map[string]interface{}{
+			"arn":                         project.ARN,
+			"name":                        project.Name,
+			"repositoryPaths":             repositoryPaths,
+			"serviceRole":                 project.ServiceRole,
+			"account":                     account,
+			"hasWebhook":                  project.Webhook != nil,
+			"buildsPullRequests":          buildsPullRequests,
+			"buildsUntrustedPullRequests": buildsUntrustedPullRequests,
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $projects AS project
+
+	MERGE (p:CodeBuildProject{id: project.arn})
+
+	SET p.name = project.name,
+	p.arn = project.arn,
+	p.hasWebhook = project.hasWebhook,
+	p.buildsPullRequests = project.buildsPullRequests,
+	p.buildsUntrustedPullRequests = project.buildsUntrustedPullRequests,
+	p.session = $session
+
+	WITH p, project
+
+	OPTIONAL MATCH (p)-[rel:RUNS_AS]->()
+	DELETE rel
+
+	WITH p, project
+	WHERE project.serviceRole <> ""
+
+	MERGE (c:CloudIdentity{id: "aws:" + project.serviceRole})
+	SET c.provider = "aws",
+	c.type = "role",
+	c.identifier = project.serviceRole,
+	c.account = project.account,
+	c.session = $session
+
+	MERGE (p)-[rel:RUNS_AS]->(c)
+	SET rel.session = $session
+	`, map[string]interface{}{"projects": projects, "session": ing.session})
+
+	ing.db.Run(`
+	UNWIND $projects AS project
+
+	MATCH (p:CodeBuildProject{id: project.arn})
+	OPTIONAL MATCH (:Anyone)-[rel:CAN_RUN_CODE_WITH_SECRETS]->(p)
+	DELETE rel
+
+	WITH p, project
+	WHERE project.buildsUntrustedPullRequests
+
+	MERGE (a:Anyone{id: "anyone"})
+	MERGE (a)-[rel:CAN_RUN_CODE_WITH_SECRETS]->(p)
+	SET rel.session = $session
+	`, map[string]interface{}{"projects": projects, "session": ing.session})
+
+	ing.db.Run(`
+	UNWIND $projects AS project
+	UNWIND project.repositoryPaths AS repositoryPath
+
+	MATCH (p:CodeBuildProject{id: project.arn})
+	MATCH (r:Repository)-[:OWNED_BY]->(:Organization{login: $organization})
+	WHERE toLower(r.id) ENDS WITH "/" + toLower(repositoryPath)
+	MERGE (r)-[rel:HAS_CI]->(p)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"projects":     projects,
+		"organization": ing.organization,
+		"session":      ing.session,
+	})
+}
+
+// Returns whether a webhook's filter groups build pull requests, and whether they build pull requests
+// from anyone. A filter group matches pull request events if its EVENT filter does, and only lets
+// specific actors through if it has an ACTOR_ACCOUNT_ID filter, which keeps out pull requests from
+// forks by others. Any matching filter group triggers a build, and webhooks without filter groups
+// build every push and pull request.
+func webhookPullRequests(filterGroups [][]CodeBuildWebhookFilter) (bool, bool) {
+	if len(filterGroups) == 0 {
+		return true, true
+	}
+
+	buildsPullRequests, buildsUntrustedPullRequests := false, false
+	for _, group := range filterGroups {
+		pullRequests, actorRestricted := false, false
+		for _, filter := range group {
+			switch {
+			case filter.Type == "EVENT" && !filter.ExcludeMatchedPattern &&
+				strings.Contains(filter.Pattern, "PULL_REQUEST_"):
+				pullRequests = true
+			case filter.Type == "ACTOR_ACCOUNT_ID" && !filter.ExcludeMatchedPattern:
+				actorRestricted = true
+			}
+		}
+		buildsPullRequests = buildsPullRequests || pullRequests
+		buildsUntrustedPullRequests = buildsUntrustedPullRequests || pullRequests && !actorRestricted
+	}
+	return buildsPullRequests, buildsUntrustedPullRequests
+}
@@ -0,0 +1,83 @@
+package cloudci
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestWebhookPullRequests(t *testing.T) {
+	testCases := []struct {
+		name         string
+		filterGroups string
+		pullRequests bool
+		untrusted    bool
+	}{
+		{
+			name:         "no filter groups",
+			filterGroups: `[]`,
+			pullRequests: true,
+			untrusted:    true,
+		},
+		{
+			name:         "pushes only",
+			filterGroups: `[[{"type": "EVENT", "pattern": "PUSH"}]]`,
+		},
+		{
+			name: "pull requests",
+			filterGroups: `[[
+				{"type": "EVENT", "pattern": "PULL_REQUEST_CREATED,PULL_REQUEST_UPDATED"},
+				{"type": "BASE_REF", "pattern": "^refs/heads/main$"}
+			]]`,
+			pullRequests: true,
+			untrusted:    true,
+		},
+		{
+			name: "pull requests from specific actors",
+			filterGroups: `[[
+				{"type": "EVENT", "pattern": "PULL_REQUEST_CREATED"},
+				{"type": "ACTOR_ACCOUNT_ID", "pattern": "^(1234|5678)$"}
+			]]`,
+			pullRequests: true,
+		},
+		{
+			name: "pull requests excluding specific actors",
+			filterGroups: `[[
+				{"type": "EVENT", "pattern": "PULL_REQUEST_CREATED"},
+				{"type": "ACTOR_ACCOUNT_ID", "pattern": "^1234$", "excludeMatchedPattern": true}
+			]]`,
+			pullRequests: true,
+			untrusted:    true,
+		},
+		{
+			name: "excluded pull requests",
+			filterGroups: `[[
+				{"type": "EVENT", "pattern": "PULL_REQUEST_CREATED", "excludeMatchedPattern": true}
+			]]`,
+		},
+		{
+			name: "actor restricted group and open group",
+			filterGroups: `[
+				[{"type": "EVENT", "pattern": "PULL_REQUEST_CREATED"}, {"type": "ACTOR_ACCOUNT_ID", "pattern": "1234"}],
+				[{"type": "EVENT", "pattern": "PULL_REQUEST_REOPENED"}]
+			]`,
+			pullRequests: true,
+			untrusted:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filterGroups := [][]CodeBuildWebhookFilter{}
+			if err := json.Unmarshal([]byte(tc.filterGroups), &filterGroups); err != nil {
+				t.Fatal(err)
+			}
+			pullRequests, untrusted := webhookPullRequests(filterGroups)
+			if pullRequests != tc.pullRequests || untrusted != tc.untrusted {
+				t.Errorf(
+					"webhookPullRequests() = %v, %v, want %v, %v",
+					pullRequests, untrusted, tc.pullRequests, tc.untrusted,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package enrich
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Matches full length commit SHAs, the only immutable way to reference an action.
+var commitSHARegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// A reference to an action or reusable workflow in another repository, e.g.
+// "actions/checkout@v3" or "octo-org/workflows/.github/workflows/deploy.yml@main".
+type actionRef struct {
+	uses  string
+	owner string
+	repo  string
+	path  string
+	ref   string
+}
+
+// Parses the value of a `uses` key. Returns false for local actions and Docker images, which don't
+// live in another repository.
+func parseActionRef(uses string) (actionRef, bool) {
+	if uses == "" || strings.HasPrefix(uses, "./") || strings.HasPrefix(uses, "docker://") {
+		return actionRef{}, false
+	}
+
+	parts := strings.SplitN(uses, "@", 2)
+	if len(parts) != 2 {
+		return actionRef{}, false
+	}
+	segments := strings.SplitN(parts[0], "/", 3)
+	if len(segments) < 2 {
+		return actionRef{}, false
+	}
+
+	action := actionRef{
+		uses:  uses,
+		owner: segments[0],
+		repo:  segments[1],
+		ref:   parts[1],
+	}
+	if len(segments) == 3 {
+		action.path = segments[2]
+	}
+	return action, true
+}
+
+// Returns true if the action is pinned to a full length commit SHA.
+func (a actionRef) pinned() bool {
+	return commitSHARegex.MatchString(a.ref)
+}
+
+// Creates Action nodes for the actions and reusable workflows that workflows depend on, and links
+// them from the files and jobs using them. A compromise of an action gives access to the secrets
+// of the jobs using it. Actions are identified by their `uses` value, including the ref, and are
+// shared across repositories. USES_ACTION relationships from earlier runs were deleted by
+// deleteDerivedData, so re-pinned or removed actions don't linger.
+func (e *Enricher) enrichActions() {
+	workflows := e.getWorkflows()
+	rows := []map[string]interface{}{}
+
+	for _, wf := range workflows {
+		for _, job := range wf.workflow.jobs {
+			uses := []string{job.uses}
+			for _, step := range job.steps {
+				uses = append(uses, step.uses)
+			}
+
+			for _, u := range uses {
+				action, ok := parseActionRef(u)
+				if !ok {
+					continue
+				}
+				rows = append(rows, map[string]interface{}{
+					"id":       action.uses,
+					"name":     strings.TrimSuffix(action.owner+"/"+action.repo+"/"+action.path, "/"),
+					"owner":    action.owner,
+					"repo":     action.repo,
+					"path":     action.path,
+					"ref":      action.ref,
+					"pinned":   action.pinned(),
+					"internal": strings.EqualFold(action.owner, e.organization),
+					"fileID":   wf.file.id,
+					"jobID":    workflowJobID(wf.file.id, job),
+					"repoID":   wf.file.repoID,
+				})
+			}
+		}
+	}
+
+	e.runBatched(`
+	UNWIND $actions AS action
+
+	MERGE (a:Action{id: action.id})
+
+	SET a.name = action.name,
+	a.owner = action.owner,
+	a.repo = action.repo,
+	a.path = action.path,
+	a.ref = action.ref,
+	a.pinned = action.pinned,
+	a.internal = action.internal,
+	a.session = $session
+
+	WITH a, action
+
+	MATCH (f:File{id: action.fileID})
+	MERGE (f)-[rel:USES_ACTION]->(a)
+	SET rel.enrichedFrom = action.repoID,
+	rel.session = $session
+
+	WITH a, action
+
+	MATCH (j:Job{id: action.jobID})
+	MERGE (j)-[rel:USES_ACTION]->(a)
+	SET rel.enrichedFrom = action.repoID,
+	rel.session = $session
+	`, "actions", rows)
+}
@@ -0,0 +1,68 @@
+package enrich
+
+import "testing"
+
+func TestParseActionRef(t *testing.T) {
+	testCases := []struct {
+		uses   string
+		ok     bool
+		want   actionRef
+		pinned bool
+	}{
+		{uses: "./.github/actions/build", ok: false},
+		{uses: "docker://alpine:3.8", ok: false},
+		{uses: "", ok: false},
+		{uses: "actions/checkout", ok: false},
+		{uses: "checkout@v3", ok: false},
+		{
+			uses:   "actions/checkout@8e5e7e5ab8b370d6c329ec480221332ada57f0ab",
+			ok:     true,
+			want:   actionRef{uses: "actions/checkout@8e5e7e5ab8b370d6c329ec480221332ada57f0ab", owner: "actions", repo: "checkout", ref: "8e5e7e5ab8b370d6c329ec480221332ada57f0ab"},
+			pinned: true,
+		},
+		{
+			uses: "actions/checkout@v3",
+			ok:   true,
+			want: actionRef{uses: "actions/checkout@v3", owner: "actions", repo: "checkout", ref: "v3"},
+		},
+		{
+			uses: "actions/checkout@v3.5.2",
+			ok:   true,
+			want: actionRef{uses: "actions/checkout@v3.5.2", owner: "actions", repo: "checkout", ref: "v3.5.2"},
+		},
+		{
+			uses: "octo-org/actions/deploy@main",
+			ok:   true,
+			want: actionRef{uses: "octo-org/actions/deploy@main", owner: "octo-org", repo: "actions", path: "deploy", ref: "main"},
+		},
+		{
+			uses: "octo-org/workflows/.github/workflows/deploy.yml@release/v1",
+			ok:   true,
+			want: actionRef{uses: "octo-org/workflows/.github/workflows/deploy.yml@release/v1", owner: "octo-org", repo: "workflows", path: ".github/workflows/deploy.yml", ref: "release/v1"},
+		},
+		{
+			// short SHAs aren't immutable
+			uses: "actions/checkout@8e5e7e5",
+			ok:   true,
+			want: actionRef{uses: "actions/checkout@8e5e7e5", owner: "actions", repo: "checkout", ref: "8e5e7e5"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.uses, func(t *testing.T) {
+			action, ok := parseActionRef(tc.uses)
+			if ok != tc.ok {
+				t.Fatalf("parseActionRef() ok = %v, want %v", ok, tc.ok)
+			}
+			if !ok {
+				return
+			}
+			if action != tc.want {
+				t.Errorf("parseActionRef() = %+v, want %+v", action, tc.want)
+			}
+			if action.pinned() != tc.pinned {
+				t.Errorf("pinned() = %v, want %v", action.pinned(), tc.pinned)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package enrich
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Cypher regular expression matching the paths of CircleCI configuration files.
+const circleCIConfigPathRegex = `\.circleci/config\.ya?ml`
+
+// A job of a CircleCI workflow requesting contexts.
+type circleCIJob struct {
+	name     string
+	contexts []string
+	// branch filters, no `only` filter means all branches
+	onlyBranches    []string
+	ignoredBranches []string
+	// orbs whose code the job runs, e.g. "circleci/aws-ecr@7.0.0"
+	orbs []string
+}
+
+// The use of a context by a project, aggregated across the jobs requesting it.
+type circleCIContextUse struct {
+	repoID          string
+	context         string
+	branches        map[string]bool
+	ignoredBranches map[string]bool
+	jobs            map[string]bool
+	orbs            map[string]bool
+}
+
+// Parses the jobs of a CircleCI configuration's workflows which request contexts.
+func parseCircleCIJobs(text string) ([]circleCIJob, error) {
+	root := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(text), &root); err != nil {
+		return nil, err
+	}
+	jobs := []circleCIJob{}
+	if len(root.Content) == 0 {
+		return jobs, nil
+	}
+	config := root.Content[0]
+
+	orbs := map[string]string{}
+	for name, orb := range mappingPairs(mappingValue(config, "orbs")) {
+		// inline orbs are defined in the configuration itself
+		if ref := scalarValue(orb); ref != "" {
+			orbs[name] = ref
+		}
+	}
+
+	// orbs used by the steps of the jobs defined in the configuration
+	jobOrbs := map[string][]string{}
+	for name, job := range mappingPairs(mappingValue(config, "jobs")) {
+		for _, step := range sequenceItems(mappingValue(job, "steps")) {
+			command := scalarValue(step)
+			for key := range mappingPairs(step) {
+				command = key
+			}
+			if orb := circleCIOrb(command, orbs); orb != "" {
+				jobOrbs[name] = append(jobOrbs[name], orb)
+			}
+		}
+	}
+
+	for _, workflow := range mappingPairs(mappingValue(config, "workflows")) {
+		for _, item := range sequenceItems(mappingValue(workflow, "jobs")) {
+			job := circleCIJob{name: scalarValue(item)}
+			for name, params := range mappingPairs(item) {
+				job.name = name
+				job.contexts = scalarValues(mappingValue(params, "context"))
+				branches := mappingValue(mappingValue(params, "filters"), "branches")
+				job.onlyBranches = scalarValues(mappingValue(branches, "only"))
+				job.ignoredBranches = scalarValues(mappingValue(branches, "ignore"))
+			}
+			if len(job.contexts) == 0 {
+				continue
+			}
+
+			job.orbs = jobOrbs[job.name]
+			if orb := circleCIOrb(job.name, orbs); orb != "" {
+				job.orbs = append(job.orbs, orb)
+			}
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs, nil
+}
+
+// Returns the orb a job or command name such as "aws-ecr/build-and-push-image" refers to, or an
+// empty string if it isn't an orb job or command.
+func circleCIOrb(name string, orbs map[string]string) string {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return orbs[parts[0]]
+}
+
+// Returns the key and value nodes of a mapping node.
+func mappingPairs(node *yaml.Node) map[string]*yaml.Node {
+	pairs := map[string]*yaml.Node{}
+	if node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node == nil || node.Kind != yaml.MappingNode {
+		return pairs
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs[node.Content[i].Value] = node.Content[i+1]
+	}
+	return pairs
+}
+
+// Returns the items of a sequence node.
+func sequenceItems(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return []*yaml.Node{}
+	}
+	return node.Content
+}
+
+// Links CircleCI projects to the contexts their configuration's workflows request, along with the
+// branches the jobs run on and the orbs running with the context's secrets. Configurations from all
+// ingested branches are considered, as CircleCI runs a branch's own configuration. Contexts are
+// those of the project's organization, and contexts restricted to projects can only be used by the
+// projects of their latest restrictions.
+func (e *Enricher) enrichCircleCIContexts() {
+	uses := map[string]*circleCIContextUse{}
+
+	for _, file := range e.getFiles(circleCIConfigPathRegex) {
+		jobs, err := parseCircleCIJobs(file.text)
+		if err != nil {
+			log.Debugf("Skipping CircleCI config %s (%s): %s", file.path, file.repoID, err)
+			continue
+		}
+
+		for _, job := range jobs {
+			for _, context := range job.contexts {
+				key := file.repoID + context
+				if _, ok := uses[key]; !ok {
+					uses[key] = &circleCIContextUse{
+						repoID:          file.repoID,
+						context:         context,
+						branches:        map[string]bool{},
+						ignoredBranches: map[string]bool{},
+						jobs:            map[string]bool{},
+						orbs:            map[string]bool{},
+					}
+				}
+				use := uses[key]
+
+				use.jobs[job.name] = true
+				for _, orb := range job.orbs {
+					use.orbs[orb] = true
+				}
+				for _, branch := range job.ignoredBranches {
+					use.ignoredBranches[branch] = true
+				}
+				if len(job.onlyBranches) == 0 {
+					use.branches["*"] = true
+				}
+				for _, branch := range job.onlyBranches {
+					use.branches[branch] = true
+				}
+			}
+		}
+	}
+
+	e.insertCircleCIContextUses(uses)
+}
+
+func (e *Enricher) insertCircleCIContextUses(uses map[string]*circleCIContextUse) {
+	rows := []map[string]interface{}{}
+	for _, use := range uses {
+		branches := sortedKeys(use.branches)
+		if use.branches["*"] {
+			branches = []string{"*"}
+		}
+		rows = append(rows, map[string]interface{}{
+			"repoID":          use.repoID,
+			"context":         use.context,
+			"branches":        branches,
+			"ignoredBranches": sortedKeys(use.ignoredBranches),
+			"jobs":            sortedKeys(use.jobs),
+			"orbs":            sortedKeys(use.orbs),
+		})
+	}
+
+	e.runBatched(`
+	UNWIND $uses AS use
+
+	MATCH (:Repository{id: use.repoID})-[:HAS_CI]->(p:CircleCIProject)
+	MATCH (c:CircleCIContext{name: use.context, organization: p.organization})
+	WHERE coalesce(c.projectRestricted, false) = false
+	OR (c)-[:RESTRICTED_TO{session: c.session}]->(p)
+	MERGE (p)-[rel:USES_CONTEXT]->(c)
+
+	SET rel.branches = use.branches,
+	rel.ignoredBranches = use.ignoredBranches,
+	rel.jobs = use.jobs,
+	rel.orbs = use.orbs,
+	rel.enrichedFrom = use.repoID,
+	rel.session = $session
+	`, "uses", rows)
+}
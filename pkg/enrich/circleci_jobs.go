@@ -0,0 +1,206 @@
+package enrich
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Parameters of orb jobs and commands assuming AWS roles with OIDC, e.g. the aws-cli orb's
+// `role_arn` or the aws-ecr orb's `role-arn`.
+var circleCIRoleParams = []string{"role_arn", "role-arn"}
+
+// What a CircleCI job runs on and the cloud identities it assumes with OIDC.
+type circleCIJobResources struct {
+	name          string
+	resourceClass string
+	identities    []cloudIdentity
+}
+
+// Parses the resource classes and OIDC identities of the jobs of a CircleCI configuration, both
+// the jobs it defines and the orb jobs its workflows use.
+func parseCircleCIJobResources(text string) ([]circleCIJobResources, error) {
+	root := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(text), &root); err != nil {
+		return nil, err
+	}
+	jobs := []circleCIJobResources{}
+	if len(root.Content) == 0 {
+		return jobs, nil
+	}
+	config := root.Content[0]
+	executors := mappingPairs(mappingValue(config, "executors"))
+
+	for name, job := range mappingPairs(mappingValue(config, "jobs")) {
+		resourceClass := scalarValue(mappingValue(job, "resource_class"))
+		if resourceClass == "" {
+			executor := mappingValue(job, "executor")
+			executorName := scalarValue(executor)
+			if executorName == "" {
+				executorName = scalarValue(mappingValue(executor, "name"))
+			}
+			resourceClass = scalarValue(mappingValue(executors[executorName], "resource_class"))
+		}
+		jobs = append(jobs, circleCIJobResources{
+			name:          name,
+			resourceClass: resourceClass,
+			identities:    circleCIOIDCIdentities(job),
+		})
+	}
+
+	for _, workflow := range mappingPairs(mappingValue(config, "workflows")) {
+		for _, item := range sequenceItems(mappingValue(workflow, "jobs")) {
+			for name, params := range mappingPairs(item) {
+				identities := circleCIOIDCIdentities(params)
+				if len(identities) > 0 {
+					jobs = append(jobs, circleCIJobResources{name: name, identities: identities})
+				}
+			}
+		}
+	}
+
+	return jobs, nil
+}
+
+// Returns the cloud identities assumed with OIDC under a node: identities found in jobs reading the
+// CIRCLE_OIDC_TOKEN environment variables, or passed as role parameters to orbs.
+func circleCIOIDCIdentities(node *yaml.Node) []cloudIdentity {
+	identities := []cloudIdentity{}
+
+	values := scalarValues(node)
+	text := strings.Join(values, "\n")
+	if strings.Contains(text, "CIRCLE_OIDC_TOKEN") {
+		identities = append(identities, textCloudIdentities(text)...)
+	}
+
+	for _, param := range circleCIRoleParams {
+		for _, value := range scalarValues(mappingValueDeep(node, param)) {
+			if awsRoleARNRegex.MatchString(value) {
+				identities = append(identities, newAWSRole(awsRoleARNRegex.FindString(value)))
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	unique := []cloudIdentity{}
+	for _, identity := range identities {
+		if !seen[identity.id()] {
+			seen[identity.id()] = true
+			unique = append(unique, identity)
+		}
+	}
+	return unique
+}
+
+// Returns a sequence node holding the values of key in all mappings under node.
+func mappingValueDeep(node *yaml.Node, key string) *yaml.Node {
+	values := &yaml.Node{Kind: yaml.SequenceNode}
+	if node == nil {
+		return values
+	}
+	if value := mappingValue(node, key); value != nil {
+		values.Content = append(values.Content, value)
+	}
+	children := node.Content
+	if node.Kind == yaml.AliasNode {
+		children = []*yaml.Node{node.Alias}
+	}
+	for _, child := range children {
+		values.Content = append(values.Content, mappingValueDeep(child, key).Content...)
+	}
+	return values
+}
+
+// Links CircleCI projects to the self-hosted runners their jobs run on, i.e. namespaced resource
+// classes, and to the cloud identities their jobs assume with CircleCI's OIDC tokens.
+func (e *Enricher) enrichCircleCIJobs() {
+	// jobs and rows by repository and runner or identity
+	runners := map[string]map[string]bool{}
+	runnerRowsByKey := map[string]map[string]interface{}{}
+	identities := map[string]map[string]bool{}
+	identityRowsByKey := map[string]map[string]interface{}{}
+
+	for _, file := range e.getFiles(circleCIConfigPathRegex) {
+		jobs, err := parseCircleCIJobResources(file.text)
+		if err != nil {
+			log.Debugf("Skipping CircleCI config %s (%s): %s", file.path, file.repoID, err)
+			continue
+		}
+
+		for _, job := range jobs {
+			if strings.Contains(job.resourceClass, "/") {
+				key := file.repoID + job.resourceClass
+				if _, ok := runners[key]; !ok {
+					runners[key] = map[string]bool{}
+					runnerRowsByKey[key] = map[string]interface{}{
+						"repoID":        file.repoID,
+						"resourceClass": job.resourceClass,
+						"namespace":     strings.SplitN(job.resourceClass, "/", 2)[0],
+					}
+				}
+				runners[key][job.name] = true
+			}
+
+			for _, identity := range job.identities {
+				key := file.repoID + identity.id()
+				if _, ok := identities[key]; !ok {
+					identities[key] = map[string]bool{}
+					identityRowsByKey[key] = map[string]interface{}{
+						"repoID":     file.repoID,
+						"id":         identity.id(),
+						"provider":   identity.provider,
+						"kind":       identity.kind,
+						"identifier": identity.identifier,
+						"account":    identity.account,
+					}
+				}
+				identities[key][job.name] = true
+			}
+		}
+	}
+
+	runnerRows := []map[string]interface{}{}
+	for key, jobs := range runners {
+		runnerRowsByKey[key]["jobs"] = sortedKeys(jobs)
+		runnerRows = append(runnerRows, runnerRowsByKey[key])
+	}
+	identityRows := []map[string]interface{}{}
+	for key, jobs := range identities {
+		identityRowsByKey[key]["jobs"] = sortedKeys(jobs)
+		identityRows = append(identityRows, identityRowsByKey[key])
+	}
+
+	e.runBatched(`
+	UNWIND $runners AS runner
+
+	MATCH (:Repository{id: runner.repoID})-[:HAS_CI]->(p:CircleCIProject)
+	MERGE (r:Runner{id: runner.resourceClass})
+	ON CREATE SET r.resourceClass = runner.resourceClass,
+	r.namespace = runner.namespace,
+	r.platform = "circleci"
+	SET r.session = $session
+
+	MERGE (p)-[rel:RUNS_ON]->(r)
+	SET rel.jobs = runner.jobs,
+	rel.enrichedFrom = runner.repoID,
+	rel.session = $session
+	`, "runners", runnerRows)
+
+	e.runBatched(`
+	UNWIND $identities AS identity
+
+	MATCH (:Repository{id: identity.repoID})-[:HAS_CI]->(p:CircleCIProject)
+	MERGE (c:CloudIdentity{id: identity.id})
+	SET c.provider = identity.provider,
+	c.type = identity.kind,
+	c.identifier = identity.identifier,
+	c.account = identity.account,
+	c.session = $session
+
+	MERGE (p)-[rel:CAN_ASSUME_VIA_OIDC]->(c)
+	SET rel.jobs = identity.jobs,
+	rel.enrichedFrom = identity.repoID,
+	rel.session = $session
+	`, "identities", identityRows)
+}
@@ -0,0 +1,61 @@
+package enrich
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParseCircleCIJobResources(t *testing.T) {
+	jobs, err := parseCircleCIJobResources(`
+version: 2.1
+orbs:
+  aws-cli: circleci/aws-cli@3
+executors:
+  runner:
+    machine: true
+    resource_class: octo-org/deployers
+jobs:
+  build:
+    docker:
+      - image: cimg/go:1.20
+    resource_class: large
+  deploy:
+    executor: runner
+    steps:
+      - run: |
+          aws sts assume-role-with-web-identity --web-identity-token $CIRCLE_OIDC_TOKEN \
+            --role-arn arn:aws:iam::123456789012:role/deployer
+  release:
+    executor:
+      name: runner
+workflows:
+  main:
+    jobs:
+      - build
+      - aws-cli/setup:
+          role_arn: arn:aws:iam::123456789012:role/releaser
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].name < jobs[j].name })
+
+	want := []circleCIJobResources{
+		{name: "aws-cli/setup", identities: []cloudIdentity{newAWSRole("arn:aws:iam::123456789012:role/releaser")}},
+		{name: "build", resourceClass: "large", identities: []cloudIdentity{}},
+		{
+			name:          "deploy",
+			resourceClass: "octo-org/deployers",
+			identities:    []cloudIdentity{newAWSRole("arn:aws:iam::123456789012:role/deployer")},
+		},
+		{name: "release", resourceClass: "octo-org/deployers", identities: []cloudIdentity{}},
+	}
+	if !reflect.DeepEqual(jobs, want) {
+		t.Errorf("parseCircleCIJobResources() = %+v, want %+v", jobs, want)
+	}
+
+	if _, err := parseCircleCIJobResources("jobs: ["); err == nil {
+		t.Error("parseCircleCIJobResources() of invalid YAML should fail")
+	}
+}
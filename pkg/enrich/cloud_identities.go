@@ -0,0 +1,203 @@
+package enrich
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Regular expression matching the paths of CI/CD configuration files, in which we look for cloud
+// identities. Other files (e.g. CODEOWNERS or build.sbt) may mention identities without
+// authenticating as them.
+const ciConfigPathRegex = workflowPathRegex + "|" +
+	actionPathRegex + "|" +
+	circleCIConfigPathRegex + "|" +
+	jenkinsfilePathRegex + "|" +
+	travisConfigPathRegex + "|" +
+	`(.*/)?buildspec[^/]*\.ya?ml|` +
+	`(.*/)?cloudbuild[^/]*\.(ya?ml|json)|` +
+	`\.gitlab-ci\.ya?ml|` +
+	`azure-pipelines\.ya?ml|` +
+	`bitbucket-pipelines\.ya?ml|` +
+	`\.drone\.ya?ml|` +
+	`\.buildkite/[^/]+\.ya?ml`
+
+var (
+	awsRoleARNRegex = regexp.MustCompile(
+		`arn:aws[a-z-]*:iam::[0-9]{12}:role/[A-Za-z0-9+=,.@_/-]+`,
+	)
+	gcpServiceAccountRegex = regexp.MustCompile(
+		`[a-z][a-z0-9-]{4,28}[a-z0-9]@[a-z][a-z0-9-]{4,28}[a-z0-9]\.iam\.gserviceaccount\.com`,
+	)
+	gcpWorkloadIdentityProviderRegex = regexp.MustCompile(
+		`projects/[0-9]+/locations/global/workloadIdentityPools/[a-z0-9-]+/providers/[a-z0-9-]+`,
+	)
+)
+
+// A cloud principal that CI/CD jobs authenticate as.
+type cloudIdentity struct {
+	provider   string
+	kind       string
+	identifier string
+	// AWS account ID or GCP project
+	account string
+}
+
+// Cloud identities are unique per provider.
+func (c cloudIdentity) id() string {
+	return c.provider + ":" + c.identifier
+}
+
+func newAWSRole(arn string) cloudIdentity {
+	return cloudIdentity{
+		provider:   "aws",
+		kind:       "role",
+		identifier: arn,
+		account:    strings.Split(arn, ":")[4],
+	}
+}
+
+func newGCPServiceAccount(email string) cloudIdentity {
+	domain := strings.SplitN(email, "@", 2)[1]
+	return cloudIdentity{
+		provider:   "gcp",
+		kind:       "serviceAccount",
+		identifier: email,
+		account:    strings.TrimSuffix(domain, ".iam.gserviceaccount.com"),
+	}
+}
+
+func newGCPWorkloadIdentityProvider(name string) cloudIdentity {
+	return cloudIdentity{
+		provider:   "gcp",
+		kind:       "workloadIdentityProvider",
+		identifier: name,
+		account:    strings.Split(name, "/")[1],
+	}
+}
+
+func newAzureClientID(clientID, tenantID string) cloudIdentity {
+	return cloudIdentity{
+		provider:   "azure",
+		kind:       "clientId",
+		identifier: clientID,
+		account:    tenantID,
+	}
+}
+
+// Returns the cloud identities a workflow step authenticates as with the official cloud
+// authentication actions. Inputs set from expressions can't be resolved and are ignored.
+func stepCloudIdentities(step *workflowStep) []cloudIdentity {
+	literal := func(input string) string {
+		value := strings.TrimSpace(step.with[input])
+		if strings.Contains(value, "${{") {
+			return ""
+		}
+		return value
+	}
+
+	identities := []cloudIdentity{}
+	switch {
+	case strings.HasPrefix(step.uses, "aws-actions/configure-aws-credentials@"):
+		if role := literal("role-to-assume"); awsRoleARNRegex.MatchString(role) {
+			identities = append(identities, newAWSRole(role))
+		}
+	case strings.HasPrefix(step.uses, "google-github-actions/auth@"):
+		if provider := literal("workload_identity_provider"); provider != "" {
+			identities = append(identities, newGCPWorkloadIdentityProvider(provider))
+		}
+		if email := literal("service_account"); gcpServiceAccountRegex.MatchString(email) {
+			identities = append(identities, newGCPServiceAccount(email))
+		}
+	case strings.HasPrefix(step.uses, "azure/login@"):
+		if clientID := literal("client-id"); clientID != "" {
+			identities = append(identities, newAzureClientID(clientID, literal("tenant-id")))
+		}
+	}
+	return identities
+}
+
+// Returns the cloud identities found in the text of any CI/CD configuration file.
+func textCloudIdentities(text string) []cloudIdentity {
+	identities := []cloudIdentity{}
+	for _, arn := range awsRoleARNRegex.FindAllString(text, -1) {
+		identities = append(identities, newAWSRole(arn))
+	}
+	for _, email := range gcpServiceAccountRegex.FindAllString(text, -1) {
+		identities = append(identities, newGCPServiceAccount(email))
+	}
+	for _, name := range gcpWorkloadIdentityProviderRegex.FindAllString(text, -1) {
+		identities = append(identities, newGCPWorkloadIdentityProvider(name))
+	}
+	return identities
+}
+
+// Creates CloudIdentity nodes for the cloud principals CI/CD files authenticate as, and links them
+// from the files, repositories and jobs using them. Identities are shared across repositories, so
+// only their relationships are derived from the repositories, see deleteDerivedData.
+func (e *Enricher) enrichCloudIdentities() {
+	rows := []map[string]interface{}{}
+	addRow := func(identity cloudIdentity, file ciFile, jobID string) {
+		rows = append(rows, map[string]interface{}{
+			"id":         identity.id(),
+			"provider":   identity.provider,
+			"kind":       identity.kind,
+			"identifier": identity.identifier,
+			"account":    identity.account,
+			"fileID":     file.id,
+			"repoID":     file.repoID,
+			"jobID":      jobID,
+		})
+	}
+
+	for _, file := range e.getFiles(ciConfigPathRegex) {
+		for _, identity := range textCloudIdentities(file.text) {
+			addRow(identity, file, "")
+		}
+	}
+
+	for _, wf := range e.getWorkflows() {
+		for _, job := range wf.workflow.jobs {
+			for _, step := range job.steps {
+				for _, identity := range stepCloudIdentities(step) {
+					addRow(identity, wf.file, workflowJobID(wf.file.id, job))
+				}
+			}
+		}
+	}
+
+	e.runBatched(`
+	UNWIND $identities AS identity
+
+	MERGE (c:CloudIdentity{id: identity.id})
+
+	SET c.provider = identity.provider,
+	c.type = identity.kind,
+	c.identifier = identity.identifier,
+	c.account = identity.account,
+	c.session = $session
+
+	WITH c, identity
+
+	MATCH (f:File{id: identity.fileID})
+	MERGE (f)-[rel:AUTHENTICATES_AS]->(c)
+	SET rel.enrichedFrom = identity.repoID,
+	rel.session = $session
+
+	WITH c, identity
+
+	MATCH (r:Repository{id: identity.repoID})
+	MERGE (r)-[rel:AUTHENTICATES_AS]->(c)
+	SET rel.enrichedFrom = identity.repoID,
+	rel.session = $session
+	`, "identities", rows)
+
+	e.runBatched(`
+	UNWIND $identities AS identity
+
+	MATCH (c:CloudIdentity{id: identity.id})
+	MATCH (j:Job{id: identity.jobID})
+	MERGE (j)-[rel:AUTHENTICATES_AS]->(c)
+	SET rel.enrichedFrom = identity.repoID,
+	rel.session = $session
+	`, "identities", rows)
+}
@@ -0,0 +1,143 @@
+package enrich
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTextCloudIdentities(t *testing.T) {
+	text := `
+aws sts assume-role --role-arn arn:aws:iam::123456789012:role/deploy/ci-deployer
+gcloud auth activate-service-account deployer@my-project-123.iam.gserviceaccount.com
+provider: projects/123456/locations/global/workloadIdentityPools/ci-pool/providers/github
+`
+
+	want := []cloudIdentity{
+		{provider: "aws", kind: "role", identifier: "arn:aws:iam::123456789012:role/deploy/ci-deployer", account: "123456789012"},
+		{provider: "gcp", kind: "serviceAccount", identifier: "deployer@my-project-123.iam.gserviceaccount.com", account: "my-project-123"},
+		{
+			provider:   "gcp",
+			kind:       "workloadIdentityProvider",
+			identifier: "projects/123456/locations/global/workloadIdentityPools/ci-pool/providers/github",
+			account:    "123456",
+		},
+	}
+	if got := textCloudIdentities(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("textCloudIdentities() = %+v, want %+v", got, want)
+	}
+	if got := want[0].id(); got != "aws:arn:aws:iam::123456789012:role/deploy/ci-deployer" {
+		t.Errorf("id() = %s", got)
+	}
+}
+
+func TestStepCloudIdentities(t *testing.T) {
+	testCases := []struct {
+		name string
+		step workflowStep
+		want []cloudIdentity
+	}{
+		{
+			name: "aws role",
+			step: workflowStep{
+				uses: "aws-actions/configure-aws-credentials@v2",
+				with: map[string]string{"role-to-assume": "arn:aws:iam::123456789012:role/deployer"},
+			},
+			want: []cloudIdentity{
+				{provider: "aws", kind: "role", identifier: "arn:aws:iam::123456789012:role/deployer", account: "123456789012"},
+			},
+		},
+		{
+			name: "aws role from an expression",
+			step: workflowStep{
+				uses: "aws-actions/configure-aws-credentials@v2",
+				with: map[string]string{"role-to-assume": "${{ secrets.ROLE_ARN }}"},
+			},
+			want: []cloudIdentity{},
+		},
+		{
+			name: "gcp workload identity",
+			step: workflowStep{
+				uses: "google-github-actions/auth@v1",
+				with: map[string]string{
+					"workload_identity_provider": "projects/123456/locations/global/workloadIdentityPools/pool/providers/github",
+					"service_account":            "deployer@my-project-123.iam.gserviceaccount.com",
+				},
+			},
+			want: []cloudIdentity{
+				{
+					provider:   "gcp",
+					kind:       "workloadIdentityProvider",
+					identifier: "projects/123456/locations/global/workloadIdentityPools/pool/providers/github",
+					account:    "123456",
+				},
+				{provider: "gcp", kind: "serviceAccount", identifier: "deployer@my-project-123.iam.gserviceaccount.com", account: "my-project-123"},
+			},
+		},
+		{
+			name: "azure",
+			step: workflowStep{
+				uses: "azure/login@v1",
+				with: map[string]string{"client-id": "00000000-0000-0000-0000-000000000001", "tenant-id": "tenant"},
+			},
+			want: []cloudIdentity{
+				{provider: "azure", kind: "clientId", identifier: "00000000-0000-0000-0000-000000000001", account: "tenant"},
+			},
+		},
+		{
+			name: "other action",
+			step: workflowStep{uses: "actions/checkout@v3", with: map[string]string{}},
+			want: []cloudIdentity{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := stepCloudIdentities(&tc.step); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("stepCloudIdentities() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCloudIdentityFiles(t *testing.T) {
+	e := &Enricher{}
+	for _, path := range []string{
+		".github/workflows/deploy.yml",
+		".github/actions/setup/action.yaml",
+		".circleci/config.yml",
+		"Jenkinsfile",
+		"ci/Jenkinsfile",
+		".travis.yml",
+		"buildspec.yml",
+		"infra/buildspec-deploy.yml",
+		"cloudbuild.yaml",
+		".gitlab-ci.yml",
+		".buildkite/pipeline.yml",
+		"CODEOWNERS",
+		"build.sbt",
+		"README.md",
+	} {
+		e.files = append(e.files, ciFile{path: path})
+	}
+
+	got := []string{}
+	for _, file := range e.getFiles(ciConfigPathRegex) {
+		got = append(got, file.path)
+	}
+	want := []string{
+		".github/workflows/deploy.yml",
+		".github/actions/setup/action.yaml",
+		".circleci/config.yml",
+		"Jenkinsfile",
+		"ci/Jenkinsfile",
+		".travis.yml",
+		"buildspec.yml",
+		"infra/buildspec-deploy.yml",
+		"cloudbuild.yaml",
+		".gitlab-ci.yml",
+		".buildkite/pipeline.yml",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getFiles(ciConfigPathRegex) = %v, want %v", got, want)
+	}
+}
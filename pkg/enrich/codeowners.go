@@ -0,0 +1,226 @@
+package enrich
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Cypher regular expression matching the paths GitHub looks for CODEOWNERS files at.
+const codeOwnersPathRegex = `(\.github/|docs/)?CODEOWNERS`
+
+// Matches the paths that should be protected by code owner reviews: changes to workflows run code
+// with the repository's secrets, and changes to CODEOWNERS can remove owners.
+var codeOwnersSensitivePathRegex = regexp.MustCompile(
+	`^(` + workflowPathRegex + `|` + codeOwnersPathRegex + `)$`,
+)
+
+// A CODEOWNERS rule. Rules without owners explicitly leave matching paths unowned.
+type codeOwnersRule struct {
+	pattern string
+	regex   *regexp.Regexp
+	owners  []string
+}
+
+// Parses the rules of a CODEOWNERS file. Invalid patterns are skipped.
+func parseCodeOwners(text string) []codeOwnersRule {
+	rules := []codeOwnersRule{}
+	for _, line := range strings.Split(text, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 && (i == 0 || line[i-1] != '\\') {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		regex, err := regexp.Compile(codeOwnersPatternRegex(fields[0]))
+		if err != nil {
+			continue
+		}
+		rules = append(rules, codeOwnersRule{
+			pattern: fields[0],
+			regex:   regex,
+			owners:  fields[1:],
+		})
+	}
+	return rules
+}
+
+// Translates a CODEOWNERS pattern, which follows gitignore rules, to a regular expression matching
+// file paths. Patterns without a slash (other than a trailing one) match at any depth. Patterns
+// naming a directory, i.e. with a trailing slash or without wildcards in their last segment, match
+// its contents, whereas wildcards don't match across slashes.
+func codeOwnersPatternRegex(pattern string) string {
+	pattern = strings.ReplaceAll(pattern, `\#`, "#")
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	directory := strings.HasSuffix(pattern, "/")
+	pattern = strings.Trim(pattern, "/")
+	lastSegment := pattern[strings.LastIndex(pattern, "/")+1:]
+
+	regex := ""
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			regex += "(.*/)?"
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			regex += ".*"
+			i++
+		case pattern[i] == '*':
+			regex += "[^/]*"
+		case pattern[i] == '?':
+			regex += "[^/]"
+		default:
+			regex += regexp.QuoteMeta(string(pattern[i]))
+		}
+	}
+
+	if !anchored {
+		regex = "(.*/)?" + regex
+	}
+	switch {
+	case directory:
+		regex += "/.*"
+	case !strings.ContainsAny(lastSegment, "*?"):
+		regex += "(/.*)?"
+	}
+	return "^" + regex + "$"
+}
+
+// Returns the owners of a file path, i.e. those of the last matching rule.
+func codeOwnersOf(rules []codeOwnersRule, filePath string) []string {
+	owners := []string{}
+	for _, rule := range rules {
+		if rule.regex.MatchString(filePath) {
+			owners = rule.owners
+		}
+	}
+	return owners
+}
+
+// Links users and teams to the repositories they are code owners of, per the CODEOWNERS file of the
+// default branch. Repositories whose branch protection requires code owner reviews get a
+// `codeOwnersUncoveredPaths` property with the workflow and CODEOWNERS files nobody owns, which can
+// be changed without a code owner's review.
+func (e *Enricher) enrichCodeOwners() {
+	// GitHub uses the first CODEOWNERS file it finds, in this order
+	locations := []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+	codeOwners := map[string]ciFile{}
+	for _, file := range e.getFiles(codeOwnersPathRegex) {
+		if file.ref != file.defaultBranch {
+			continue
+		}
+		current, ok := codeOwners[file.repoID]
+		if !ok || locationIndex(locations, file.path) < locationIndex(locations, current.path) {
+			codeOwners[file.repoID] = file
+		}
+	}
+
+	owners := []map[string]interface{}{}
+	rulesByRepo := map[string][]codeOwnersRule{}
+	for repoID, file := range codeOwners {
+		rulesByRepo[repoID] = parseCodeOwners(file.text)
+		for _, rule := range rulesByRepo[repoID] {
+			for _, owner := range rule.owners {
+				row := map[string]interface{}{"repoID": repoID, "pattern": rule.pattern}
+				parts := strings.SplitN(strings.TrimPrefix(owner, "@"), "/", 2)
+				switch {
+				// email addresses can't be resolved to users
+				case !strings.HasPrefix(owner, "@"):
+					continue
+				case len(parts) == 2 && strings.EqualFold(parts[0], e.organization):
+					row["teamPath"] = "/orgs/" + parts[0] + "/teams/" + parts[1]
+				case len(parts) == 1:
+					row["user"] = parts[0]
+				default:
+					continue
+				}
+				owners = append(owners, row)
+			}
+		}
+	}
+
+	requireReviews := e.getCodeOwnerReviewRepos()
+	repos := []map[string]interface{}{}
+	for _, repoID := range e.repoIDs() {
+		repos = append(repos, map[string]interface{}{
+			"id":             repoID,
+			"uncoveredPaths": []string{},
+		})
+	}
+	for _, repo := range repos {
+		repoID := repo["id"].(string)
+		if !requireReviews[repoID] {
+			continue
+		}
+		uncovered := []string{}
+		for _, file := range e.files {
+			if file.repoID != repoID || file.ref != file.defaultBranch {
+				continue
+			}
+			if !codeOwnersSensitivePathRegex.MatchString(file.path) {
+				continue
+			}
+			if len(codeOwnersOf(rulesByRepo[repoID], file.path)) == 0 {
+				uncovered = append(uncovered, file.path)
+			}
+		}
+		repo["uncoveredPaths"] = uncovered
+	}
+
+	e.runBatched(`
+	UNWIND $repos AS repo
+	MATCH (r:Repository{id: repo.id})
+	SET r.codeOwnersUncoveredPaths = repo.uncoveredPaths
+	`, "repos", repos)
+
+	e.runBatched(`
+	UNWIND $owners AS owner
+	MATCH (r:Repository{id: owner.repoID})
+	MATCH (t:Team)
+	WHERE toLower(t.url) ENDS WITH toLower(owner.teamPath)
+	MERGE (t)-[rel:CODE_OWNER_OF{pattern: owner.pattern}]->(r)
+	SET rel.enrichedFrom = owner.repoID,
+	rel.session = $session
+	`, "owners", owners)
+
+	e.runBatched(`
+	UNWIND $owners AS owner
+	MATCH (r:Repository{id: owner.repoID})
+	MATCH (u:User{login: owner.user})
+	MERGE (u)-[rel:CODE_OWNER_OF{pattern: owner.pattern}]->(r)
+	SET rel.enrichedFrom = owner.repoID,
+	rel.session = $session
+	`, "owners", owners)
+}
+
+// Returns the repositories being enriched with a branch protection rule requiring code owner
+// reviews on their default branch.
+func (e *Enricher) getCodeOwnerReviewRepos() map[string]bool {
+	records := e.db.Run(`
+	MATCH (r:Repository)-[:HAS_BRANCH_PROTECTION_RULE]->(b:BranchProtectionRule)
+	WHERE r.id IN $repoIDs AND b.requiresCodeOwnerReviews
+	RETURN r.id as repoID, r.defaultBranch as defaultBranch, b.pattern as pattern
+	`, map[string]interface{}{"repoIDs": e.repoIDs()})
+
+	repos := map[string]bool{}
+	for records.Next() {
+		defaultBranch := getString(records.Record().Get("defaultBranch"))
+		pattern := getString(records.Record().Get("pattern"))
+		if matched, _ := path.Match(pattern, defaultBranch); matched {
+			repos[getString(records.Record().Get("repoID"))] = true
+		}
+	}
+	return repos
+}
+
+// Returns the index of a value in a slice, or the length of the slice if it isn't found.
+func locationIndex(locations []string, value string) int {
+	for i, location := range locations {
+		if location == value {
+			return i
+		}
+	}
+	return len(locations)
+}
@@ -0,0 +1,140 @@
+package enrich
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestCodeOwnersPatternRegex(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		matches  []string
+		excludes []string
+	}{
+		{
+			pattern: "*",
+			matches: []string{"README.md", ".github/workflows/ci.yml"},
+		},
+		{
+			pattern:  "*.yml",
+			matches:  []string{"ci.yml", ".github/workflows/ci.yml"},
+			excludes: []string{"ci.yaml", "ci.yml.bak"},
+		},
+		{
+			pattern:  "docs/*",
+			matches:  []string{"docs/index.md"},
+			excludes: []string{"docs/a/b.yml", "src/docs/index.md"},
+		},
+		{
+			pattern:  "/docs/",
+			matches:  []string{"docs/index.md", "docs/a/b.yml"},
+			excludes: []string{"docs", "src/docs/index.md"},
+		},
+		{
+			pattern:  "apps/",
+			matches:  []string{"apps/api/main.go", "src/apps/web/index.js"},
+			excludes: []string{"apps"},
+		},
+		{
+			pattern:  ".github/workflows",
+			matches:  []string{".github/workflows", ".github/workflows/ci.yml"},
+			excludes: []string{"src/.github/workflows/ci.yml", ".github/workflows-old/ci.yml"},
+		},
+		{
+			pattern:  "Jenkinsfile",
+			matches:  []string{"Jenkinsfile", "ci/Jenkinsfile"},
+			excludes: []string{"Jenkinsfile.bak"},
+		},
+		{
+			pattern:  "docs/**",
+			matches:  []string{"docs/index.md", "docs/a/b.yml"},
+			excludes: []string{"src/docs/index.md"},
+		},
+		{
+			pattern:  "**/logs",
+			matches:  []string{"logs", "build/logs", "build/logs/today.log"},
+			excludes: []string{"build/logs2"},
+		},
+		{
+			pattern:  "ci/?.yml",
+			matches:  []string{"ci/a.yml"},
+			excludes: []string{"ci/ab.yml", "ci/a/b.yml"},
+		},
+		{
+			pattern: `\#notes`,
+			matches: []string{"#notes"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			regex := regexp.MustCompile(codeOwnersPatternRegex(tc.pattern))
+			for _, path := range tc.matches {
+				if !regex.MatchString(path) {
+					t.Errorf("%s (%s) doesn't match %s", tc.pattern, regex, path)
+				}
+			}
+			for _, path := range tc.excludes {
+				if regex.MatchString(path) {
+					t.Errorf("%s (%s) matches %s", tc.pattern, regex, path)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCodeOwners(t *testing.T) {
+	rules := parseCodeOwners(`
+# default owners
+*       @octo-org/everyone
+
+/.github/ @octo-org/security admin@example.com # CI/CD
+docs/*  # no owners
+\#notes @octocat
+`)
+
+	got := [][]string{}
+	for _, rule := range rules {
+		got = append(got, append([]string{rule.pattern}, rule.owners...))
+	}
+	want := [][]string{
+		{"*", "@octo-org/everyone"},
+		{"/.github/", "@octo-org/security", "admin@example.com"},
+		{"docs/*"},
+		{`\#notes`, "@octocat"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCodeOwners() = %v, want %v", got, want)
+	}
+}
+
+func TestCodeOwnersOf(t *testing.T) {
+	rules := parseCodeOwners(`
+*                   @octo-org/everyone
+/.github/           @octo-org/security
+/.github/workflows/ @octo-org/platform
+docs/*
+`)
+
+	testCases := []struct {
+		path string
+		want []string
+	}{
+		{path: "main.go", want: []string{"@octo-org/everyone"}},
+		{path: ".github/CODEOWNERS", want: []string{"@octo-org/security"}},
+		{path: ".github/workflows/ci.yml", want: []string{"@octo-org/platform"}},
+		{path: "docs/index.md", want: []string{}},
+		{path: "docs/api/index.md", want: []string{"@octo-org/everyone"}},
+	}
+
+	for _, tc := range testCases {
+		if got := codeOwnersOf(rules, tc.path); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("codeOwnersOf(%s) = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+
+	if got := codeOwnersOf([]codeOwnersRule{}, "main.go"); len(got) != 0 {
+		t.Errorf("codeOwnersOf() without rules = %v, want none", got)
+	}
+}
@@ -1,94 +1,241 @@
 package enrich
 
 import (
-	"regexp"
-	"strings"
+	"fmt"
 
+	"github.com/ovotech/gitoops/internal/utils"
 	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
 )
 
+// The maximum number of rows we write in a single query.
+const batchSize = 1000
+
 type Enricher struct {
 	db           *database.Database
 	organization string
+	session      string
+	rules        *Rules
+	// enrich all repositories rather than those whose files changed
+	full bool
+	// the files and repositories enriched during this run, see getPendingFiles
+	files []ciFile
+	repos []string
 }
 
-func GetEnricher(db *database.Database, organization string) *Enricher {
+func GetEnricher(
+	db *database.Database,
+	organization, session string,
+	rules *Rules,
+	full bool,
+) *Enricher {
 	return &Enricher{
 		db:           db,
 		organization: organization,
+		session:      session,
+		rules:        rules,
+		full:         full,
 	}
 }
 
 func (e *Enricher) Enrich() {
-	e.extractEnvVarsFromCIFiles()
-	e.tagCIFiles()
+	e.files, e.repos = e.getPendingFiles()
+	log.Infof("Enriching %d files of %d repositories", len(e.files), len(e.repos))
+
+	e.touchDerivedData()
+	e.deleteDerivedData()
+	e.applyRules()
+	e.enrichWorkflows()
+	e.enrichUntrustedCodeWorkflows()
+	e.enrichScriptInjections()
+	e.enrichActions()
+	e.enrichWorkflowCalls()
+	e.enrichCloudIdentities()
+	e.enrichOIDCTrusts()
+	e.enrichCircleCIContexts()
+	e.enrichCircleCIJobs()
+	e.enrichJenkinsfileCredentials()
+	e.enrichCodeOwners()
+	e.enrichEnvironmentVariableReferences()
+	e.markFilesEnriched()
+}
+
+// Applies the enrichment rules, label by label so each node is only read and written once.
+func (e *Enricher) applyRules() {
+	labels := []string{}
+	rulesByLabel := map[string][]*Rule{}
+	for _, rule := range e.rules.Rules {
+		if _, ok := rulesByLabel[rule.Label]; !ok {
+			labels = append(labels, rule.Label)
+		}
+		rulesByLabel[rule.Label] = append(rulesByLabel[rule.Label], rule)
+	}
+
+	for _, label := range labels {
+		e.applyLabelRules(label, rulesByLabel[label])
+	}
 }
 
-func (e *Enricher) extractEnvVarsFromCIFiles() {
-	r, _ := regexp.Compile("[A-Z_]{2,}")
+// Applies rules to the files being enriched, or the nodes with label set in this session for other
+// labels. Nodes get a `tags` property with the tags of matching tag rules, and set rules'
+// properties are set to the values they matched.
+func (e *Enricher) applyLabelRules(label string, rules []*Rule) {
+	hasTagRules := false
+	for _, rule := range rules {
+		hasTagRules = hasTagRules || rule.Tag != ""
+	}
+
+	where := "n.session = $session"
+	if label == "File" {
+		where = "n.id IN $fileIDs"
+	}
 
-	records := e.db.Run(`
-	MATCH (f:File) RETURN f.text as text, f.id as id
-	`, map[string]interface{}{})
+	// nb: labels are validated when loading rules
+	records := e.db.Run(fmt.Sprintf(`
+	MATCH (n:%s) WHERE %s RETURN n.id as id, properties(n) as properties
+	`, label, where), map[string]interface{}{"session": e.session, "fileIDs": e.fileIDs()})
 
+	nodes := []map[string]interface{}{}
 	for records.Next() {
 		id, _ := records.Record().Get("id")
-		text, _ := records.Record().Get("text")
-		matches := r.FindAllString(text.(string), -1)
-		envVars := removeDuplicateStringsFromSlice(matches)
-
-		e.db.Run(`
-		MATCH (f:File{id: $id})
-		SET f.env = $envVars
-		`, map[string]interface{}{"id": id, "envVars": envVars})
+		p, _ := records.Record().Get("properties")
+		properties, _ := p.(map[string]interface{})
+
+		tags := []string{}
+		values := map[string][]string{}
+		for _, rule := range rules {
+			value, _ := properties[rule.Property].(string)
+			matches := rule.matches(value)
+			if rule.Tag != "" && len(matches) > 0 {
+				tags = append(tags, rule.Tag)
+			}
+			if rule.Set != "" {
+				values[rule.Set] = append(values[rule.Set], matches...)
+			}
+		}
+
+		updates := map[string]interface{}{}
+		if hasTagRules {
+			updates["tags"] = utils.RemoveDuplicateStrings(tags)
+		}
+		for property, v := range values {
+			updates[property] = utils.RemoveDuplicateStrings(v)
+		}
+		nodes = append(nodes, map[string]interface{}{"id": id, "updates": updates})
 	}
+
+	e.runBatched(fmt.Sprintf(`
+	UNWIND $nodes AS node
+	MATCH (n:%s{id: node.id})
+	SET n += node.updates
+	`, label), "nodes", nodes)
 }
 
-func removeDuplicateStringsFromSlice(strSlice []string) []string {
-	allKeys := make(map[string]bool)
-	list := []string{}
-	for _, item := range strSlice {
-		if _, value := allKeys[item]; !value {
-			allKeys[item] = true
-			list = append(list, item)
-		}
+// Deletes what previous runs derived from the repositories being enriched, so enrichments recompute
+// it from scratch and nothing removed from a file lingers. Nodes and relationships derived from a
+// repository's files have an `enrichedFrom` property with the repository's ID, and a `session`
+// property. Nodes shared across repositories (e.g. actions) aren't derived from a single repository
+// and are kept.
+func (e *Enricher) deleteDerivedData() {
+	repos := []map[string]interface{}{}
+	for _, repoID := range e.repoIDs() {
+		repos = append(repos, map[string]interface{}{"id": repoID})
 	}
-	return list
+
+	e.runBatched(`
+	WITH [repo IN $repos | repo.id] AS repoIDs
+	MATCH ()-[rel]->()
+	WHERE rel.enrichedFrom IN repoIDs
+	DELETE rel
+	`, "repos", repos)
+
+	e.runBatched(`
+	WITH [repo IN $repos | repo.id] AS repoIDs
+	MATCH (n)
+	WHERE (n:Workflow OR n:Job OR n:Step) AND n.enrichedFrom IN repoIDs
+	DETACH DELETE n
+	`, "repos", repos)
+}
+
+// Sets the session on what previous runs derived from the organization's repositories we don't
+// enrich this time, as their files haven't changed. This keeps it from looking stale to anyone
+// removing data without the latest session.
+func (e *Enricher) touchDerivedData() {
+	e.db.Run(`
+	MATCH (:Organization{login: $organization})<-[:OWNED_BY]-(r:Repository)
+	WHERE NOT r.id IN $repoIDs
+	WITH collect(r.id) AS repoIDs
+
+	MATCH (a)-[rel]->(b)
+	WHERE rel.enrichedFrom IN repoIDs
+	SET rel.session = $session
+
+	WITH [n IN [a, b] WHERE n:Workflow OR n:Job OR n:Step OR n:Action OR n:CloudIdentity OR n:Runner]
+	AS derived
+	UNWIND derived AS n
+	SET n.session = $session
+	`, map[string]interface{}{
+		"organization": e.organization,
+		"repoIDs":      e.repoIDs(),
+		"session":      e.session,
+	})
 }
 
-func (e *Enricher) tagCIFiles() {
-	keyTerms := map[string][]string{
-		"aws":         {"aws", "ecr"},
-		"gcp":         {"gcp", "gcr", "gcloud"},
-		"dockerhub":   {"dockerhub"},
-		"artifactory": {"artifactory"},
-		"terraform":   {"tf", "terraform"},
-		"bintray":     {"bintray"},
-		"kafka":       {"kafka", "aiven"},
+// Records when and with which rules the files were enriched, and which files their repositories had,
+// so we can skip them next time unless they change.
+func (e *Enricher) markFilesEnriched() {
+	rows := []map[string]interface{}{}
+	repoFileIDs := map[string][]string{}
+	for _, file := range e.files {
+		rows = append(rows, map[string]interface{}{
+			"id":        file.id,
+			"rulesHash": e.rules.hash,
+		})
+		repoFileIDs[file.repoID] = append(repoFileIDs[file.repoID], file.id)
 	}
 
-	records := e.db.Run(`
-	MATCH (f:File) RETURN f.text as text, f.id as id
-	`, map[string]interface{}{})
+	e.runBatched(`
+	UNWIND $files AS file
+	MATCH (f:File{id: file.id})
+	SET f.enrichedAt = timestamp(),
+	f.enrichedRulesHash = file.rulesHash,
+	f.enrichedTextHash = f.textHash
+	`, "files", rows)
 
-	for records.Next() {
-		tags := []string{}
-		id, _ := records.Record().Get("id")
-		text, _ := records.Record().Get("text")
-		lowerText := strings.ToLower(text.(string))
-
-		for tag, terms := range keyTerms {
-			for _, term := range terms {
-				if strings.Contains(lowerText, term) {
-					tags = append(tags, tag)
-					break
-				}
-			}
+	repos := []map[string]interface{}{}
+	for _, repoID := range e.repoIDs() {
+		fileIDs := repoFileIDs[repoID]
+		if fileIDs == nil {
+			fileIDs = []string{}
 		}
+		repos = append(repos, map[string]interface{}{"id": repoID, "fileIDs": fileIDs})
+	}
 
-		e.db.Run(`
-		MATCH (f:File{id: $id})
-		SET f.tags = $tags
-		`, map[string]interface{}{"id": id, "tags": tags})
+	e.runBatched(`
+	UNWIND $repos AS repo
+	MATCH (r:Repository{id: repo.id})
+	SET r.enrichedFileIDs = repo.fileIDs
+	`, "repos", repos)
+}
+
+// Runs a query once per batch of rows, with the batch as the key parameter and the enrichment
+// session as the session parameter.
+func (e *Enricher) runBatched(query, key string, rows []map[string]interface{}) {
+	for start := 0; start < len(rows); start += batchSize {
+		end := start + batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		e.db.Run(query, map[string]interface{}{key: rows[start:end], "session": e.session})
+	}
+}
+
+// Returns true if slice s contains element e, false otherwise.
+func sliceContains(s []string, e string) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
 	}
+	return false
 }
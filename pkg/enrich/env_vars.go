@@ -0,0 +1,179 @@
+package enrich
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ovotech/gitoops/internal/utils"
+)
+
+// Regular expression matching the paths of Travis CI configuration files.
+const travisConfigPathRegex = `\.travis\.ya?ml`
+
+// Regular expression matching the paths of files run by CI/CD systems exposing environment
+// variables to the shell. GitHub Actions only does so for secrets mapped through `env:`, which
+// are referenced by expressions.
+var shellEnvVarPathRegex = regexp.MustCompile(
+	"^(?:" + circleCIConfigPathRegex + "|" + travisConfigPathRegex + "|" + jenkinsfilePathRegex + ")$",
+)
+
+// Matches shell-style references to environment variables, e.g. $NPM_TOKEN or ${NPM_TOKEN}. We
+// only consider upper case names as lower case ones are usually local shell variables.
+var shellVarRefRegex = regexp.MustCompile(`\$\{?([A-Z_][A-Z0-9_]*)\b`)
+
+// Names referenced by a file. Secrets referenced in GitHub Actions expressions have to be defined,
+// whereas shell-style references may as well be set by the CI/CD system or the file itself. Shell
+// references are only considered in files of CI/CD systems exposing variables to the shell.
+type envVarRefs struct {
+	secrets []string
+	shell   []string
+}
+
+// Returns the environment variable names referenced in a file.
+func fileEnvVarRefs(file ciFile) envVarRefs {
+	refs := envVarRefs{secrets: []string{}, shell: []string{}}
+
+	for _, expression := range expressionRegex.FindAllString(file.text, -1) {
+		for _, match := range secretRefRegex.FindAllStringSubmatch(expression, -1) {
+			name := match[1]
+			if name == "" {
+				name = match[2]
+			}
+			// secret names are case insensitive and GITHUB_TOKEN is always defined
+			name = strings.ToUpper(name)
+			if name != "GITHUB_TOKEN" {
+				refs.secrets = append(refs.secrets, name)
+			}
+		}
+	}
+
+	if shellEnvVarPathRegex.MatchString(file.path) {
+		for _, match := range shellVarRefRegex.FindAllStringSubmatch(file.text, -1) {
+			refs.shell = append(refs.shell, match[1])
+		}
+	}
+
+	refs.secrets = utils.RemoveDuplicateStrings(refs.secrets)
+	refs.shell = utils.RemoveDuplicateStrings(refs.shell)
+	return refs
+}
+
+// An environment variable exposed to a repository.
+type exposedEnvVar struct {
+	id   string
+	name string
+}
+
+// Links files to the environment variables they reference among those exposed to their repository.
+// Files get an `undefinedEnvironmentVariables` property with the secrets they reference that
+// aren't exposed to the repository, and variables an `unused` property set to true when no file
+// references them.
+func (e *Enricher) enrichEnvironmentVariableReferences() {
+	exposed := e.getExposedEnvVars(e.repoIDs())
+
+	references := []map[string]interface{}{}
+	files := []map[string]interface{}{}
+	for _, file := range e.files {
+		refs := fileEnvVarRefs(file)
+
+		undefined := []string{}
+		for _, name := range refs.secrets {
+			found := false
+			for _, v := range exposed[file.repoID] {
+				if strings.EqualFold(v.name, name) {
+					references = append(references, map[string]interface{}{
+						"fileID": file.id,
+						"repoID": file.repoID,
+						"varID":  v.id,
+					})
+					found = true
+				}
+			}
+			if !found {
+				undefined = append(undefined, name)
+			}
+		}
+		for _, name := range refs.shell {
+			for _, v := range exposed[file.repoID] {
+				if v.name == name {
+					references = append(references, map[string]interface{}{
+						"fileID": file.id,
+						"repoID": file.repoID,
+						"varID":  v.id,
+					})
+				}
+			}
+		}
+
+		files = append(files, map[string]interface{}{"id": file.id, "undefined": undefined})
+	}
+
+	e.runBatched(`
+	UNWIND $files AS file
+	MATCH (f:File{id: file.id})
+	SET f.undefinedEnvironmentVariables = file.undefined
+	`, "files", files)
+
+	e.runBatched(`
+	UNWIND $references AS reference
+	MATCH (f:File{id: reference.fileID})
+	MATCH (v:EnvironmentVariable{id: reference.varID})
+	MERGE (f)-[rel:REFERENCES_ENVIRONMENT_VARIABLE]->(v)
+	SET rel.enrichedFrom = reference.repoID,
+	rel.session = $session
+	`, "references", references)
+
+	variables := []map[string]interface{}{}
+	for _, vars := range exposed {
+		for _, v := range vars {
+			variables = append(variables, map[string]interface{}{"id": v.id})
+		}
+	}
+
+	e.runBatched(`
+	UNWIND $variables AS variable
+	MATCH (v:EnvironmentVariable{id: variable.id})
+	SET v.unused = NOT exists((:File)-[:REFERENCES_ENVIRONMENT_VARIABLE]->(v))
+	`, "variables", variables)
+}
+
+// Returns the environment variables exposed to each repository, directly (repository and
+// organization secrets), through its environments or through its CI projects (CircleCI, Travis CI)
+// and the CircleCI contexts they use.
+func (e *Enricher) getExposedEnvVars(repoIDs []string) map[string][]exposedEnvVar {
+	records := e.db.Run(`
+	MATCH (r:Repository)-[:EXPOSES_ENVIRONMENT_VARIABLE]->(v:EnvironmentVariable)
+	WHERE r.id IN $repoIDs
+	RETURN r.id as repoID, v.id as id, v.name as name
+
+	UNION
+
+	MATCH (r:Repository)-[:HAS_ENVIRONMENT]->(:Environment)
+	-[:EXPOSES_ENVIRONMENT_VARIABLE]->(v:EnvironmentVariable)
+	WHERE r.id IN $repoIDs
+	RETURN r.id as repoID, v.id as id, v.name as name
+
+	UNION
+
+	MATCH (r:Repository)-[:HAS_CI]->()-[:EXPOSES_ENVIRONMENT_VARIABLE]->(v:EnvironmentVariable)
+	WHERE r.id IN $repoIDs
+	RETURN r.id as repoID, v.id as id, v.name as name
+
+	UNION
+
+	MATCH (r:Repository)-[:HAS_CI]->(:CircleCIProject)-[:USES_CONTEXT]->(:CircleCIContext)
+	-[:EXPOSES_ENVIRONMENT_VARIABLE]->(v:EnvironmentVariable)
+	WHERE r.id IN $repoIDs
+	RETURN r.id as repoID, v.id as id, v.name as name
+	`, map[string]interface{}{"repoIDs": repoIDs})
+
+	exposed := map[string][]exposedEnvVar{}
+	for records.Next() {
+		repoID := getString(records.Record().Get("repoID"))
+		exposed[repoID] = append(exposed[repoID], exposedEnvVar{
+			id:   getString(records.Record().Get("id")),
+			name: getString(records.Record().Get("name")),
+		})
+	}
+	return exposed
+}
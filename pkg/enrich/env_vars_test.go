@@ -0,0 +1,53 @@
+package enrich
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFileEnvVarRefs(t *testing.T) {
+	testCases := []struct {
+		name string
+		file ciFile
+		want envVarRefs
+	}{
+		{
+			name: "workflow",
+			file: ciFile{path: ".github/workflows/ci.yml", text: `
+env:
+  TOKEN: ${{ secrets.npm_token }}
+steps:
+  - run: echo $TOKEN $NPM_TOKEN ${{ secrets['DEPLOY_KEY'] }} ${{ secrets.GITHUB_TOKEN }}
+`},
+			want: envVarRefs{secrets: []string{"NPM_TOKEN", "DEPLOY_KEY"}, shell: []string{}},
+		},
+		{
+			name: "circleci",
+			file: ciFile{path: ".circleci/config.yml", text: "run: npm publish --token $NPM_TOKEN ${AWS_KEY} $lower"},
+			want: envVarRefs{secrets: []string{}, shell: []string{"NPM_TOKEN", "AWS_KEY"}},
+		},
+		{
+			name: "travis",
+			file: ciFile{path: ".travis.yml", text: "script: echo $TRAVIS_TOKEN $TRAVIS_TOKEN"},
+			want: envVarRefs{secrets: []string{}, shell: []string{"TRAVIS_TOKEN"}},
+		},
+		{
+			name: "jenkinsfile",
+			file: ciFile{path: "ci/Jenkinsfile", text: `sh 'docker login -p $DOCKER_PASSWORD'`},
+			want: envVarRefs{secrets: []string{}, shell: []string{"DOCKER_PASSWORD"}},
+		},
+		{
+			name: "other file",
+			file: ciFile{path: "Makefile", text: "deploy:\n\tcurl -H $API_TOKEN"},
+			want: envVarRefs{secrets: []string{}, shell: []string{}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := fileEnvVarRefs(tc.file); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("fileEnvVarRefs() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
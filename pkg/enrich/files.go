@@ -0,0 +1,116 @@
+package enrich
+
+import "regexp"
+
+// A CI/CD configuration file and the repository it was found in.
+type ciFile struct {
+	id       string
+	path     string
+	text     string
+	ref      string
+	repoID   string
+	repoName string
+	// the default branch of the repository
+	defaultBranch string
+}
+
+// Returns the repositories of the organization that need enriching along with their files, i.e.
+// repositories with a file that was never enriched, changed since we last enriched it or was
+// enriched with other rules, and repositories whose files were added or removed since we last
+// enriched them. Files are those of the repository's latest ingestion, stale files (e.g. deleted
+// from the repository) are ignored. We enrich whole repositories as some enrichments aggregate
+// across files. All repositories are pending in full enrichments.
+func (e *Enricher) getPendingFiles() ([]ciFile, []string) {
+	records := e.db.Run(`
+	MATCH (:Organization{login: $organization})<-[:OWNED_BY]-(r:Repository)
+	OPTIONAL MATCH (r)-[:HAS_CI_CONFIGURATION_FILE]->(f:File)
+	WITH r, [f IN collect(f) WHERE f.session = r.session] AS files
+	WITH r, files, [f IN files | f.id] AS fileIDs, coalesce(r.enrichedFileIDs, []) AS enrichedFileIDs
+	WHERE $full
+		OR any(f IN files WHERE f.enrichedAt IS NULL
+		OR f.enrichedRulesHash <> $rulesHash
+		OR f.enrichedTextHash <> f.textHash)
+		OR any(id IN fileIDs WHERE NOT id IN enrichedFileIDs)
+		OR any(id IN enrichedFileIDs WHERE NOT id IN fileIDs)
+	RETURN r.id as repoID, r.name as repoName, r.defaultBranch as defaultBranch,
+	[f IN files | f {.id, .path, .text, .ref}] as files
+	`, map[string]interface{}{
+		"organization": e.organization,
+		"full":         e.full,
+		"rulesHash":    e.rules.hash,
+	})
+
+	files := []ciFile{}
+	repoIDs := []string{}
+	for records.Next() {
+		repoID := getString(records.Record().Get("repoID"))
+		repoIDs = append(repoIDs, repoID)
+
+		values, _ := records.Record().Get("files")
+		repoFiles, _ := values.([]interface{})
+		for _, value := range repoFiles {
+			file, _ := value.(map[string]interface{})
+			id, _ := file["id"].(string)
+			path, _ := file["path"].(string)
+			text, _ := file["text"].(string)
+			ref, _ := file["ref"].(string)
+			files = append(files, ciFile{
+				id:            id,
+				path:          path,
+				text:          text,
+				ref:           ref,
+				repoID:        repoID,
+				repoName:      getString(records.Record().Get("repoName")),
+				defaultBranch: getString(records.Record().Get("defaultBranch")),
+			})
+		}
+	}
+
+	return files, repoIDs
+}
+
+// Returns the files being enriched whose path fully matches pathRegex.
+func (e *Enricher) getFiles(pathRegex string) []ciFile {
+	regex := regexp.MustCompile("^(?:" + pathRegex + ")$")
+
+	files := []ciFile{}
+	for _, file := range e.files {
+		if regex.MatchString(file.path) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// Returns the IDs of the files being enriched.
+func (e *Enricher) fileIDs() []string {
+	ids := []string{}
+	for _, file := range e.files {
+		ids = append(ids, file.id)
+	}
+	return ids
+}
+
+// Returns the IDs of the repositories being enriched. Their files are the files being enriched,
+// though a repository may have none left.
+func (e *Enricher) repoIDs() []string {
+	return e.repos
+}
+
+// Returns the value of a record key as a string, or an empty string if it's not set.
+func getString(value interface{}, ok bool) string {
+	s, _ := value.(string)
+	return s
+}
+
+// Returns the value of a record key as a slice of strings, or an empty slice if it's not set.
+func getStrings(value interface{}, ok bool) []string {
+	strs := []string{}
+	values, _ := value.([]interface{})
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
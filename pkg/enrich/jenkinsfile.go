@@ -0,0 +1,36 @@
+package enrich
+
+import "github.com/ovotech/gitoops/pkg/jenkins"
+
+// Regular expression matching the paths of Jenkinsfiles.
+const jenkinsfilePathRegex = `(.*/)?Jenkinsfile`
+
+// Links Jenkins jobs building a repository to the credentials referenced in the repository's
+// Jenkinsfile, i.e. the file at the script path of pipeline and multibranch jobs. Like Jenkins, we
+// resolve credential IDs from the job's closest folder up to the global store.
+func (e *Enricher) enrichJenkinsfileCredentials() {
+	files := []map[string]interface{}{}
+	for _, file := range e.getFiles(jenkinsfilePathRegex) {
+		files = append(files, map[string]interface{}{
+			"repoID":        file.repoID,
+			"path":          file.path,
+			"credentialIds": jenkins.CredentialRefs(file.text),
+		})
+	}
+
+	e.runBatched(`
+	UNWIND $files AS file
+	MATCH (:Repository{id: file.repoID})-[:HAS_CI]->(j:JenkinsJob)
+	WHERE j.scriptPath = file.path
+	UNWIND file.credentialIds AS credentialId
+
+	MATCH (c:JenkinsCredential{credentialId: credentialId, jenkinsURL: j.jenkinsURL})
+	WHERE c.folder = "" OR j.fullName STARTS WITH c.folder + "/"
+	WITH file, j, credentialId, c ORDER BY size(c.folder) DESC
+	WITH file, j, credentialId, collect(c)[0] AS c
+
+	MERGE (j)-[rel:USES_CREDENTIAL{source: "Jenkinsfile"}]->(c)
+	SET rel.enrichedFrom = file.repoID,
+	rel.session = $session
+	`, "files", files)
+}
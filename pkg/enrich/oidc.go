@@ -0,0 +1,212 @@
+package enrich
+
+import (
+	"sort"
+	"strings"
+)
+
+// Claims making up the subject of GitHub Actions OIDC tokens unless customized.
+var defaultOIDCSubClaimKeys = []string{"repo", "context"}
+
+// A source that can mint OIDC tokens for a cloud identity, along with the branches it can do so
+// from and the subject claims of the tokens.
+type oidcTrust struct {
+	repoID      string
+	environment string
+	identityID  string
+	refs        map[string]bool
+	subjects    map[string]bool
+}
+
+// Links repositories and environments to the cloud identities their workflows can assume with
+// GitHub's OIDC provider, i.e. jobs with the `id-token: write` permission authenticating to a
+// cloud with OIDC.
+func (e *Enricher) enrichOIDCTrusts() {
+	claimKeys := e.getOIDCSubClaimKeys()
+	trusts := map[string]*oidcTrust{}
+
+	for _, wf := range e.getWorkflows() {
+		for _, job := range wf.workflow.jobs {
+			if !canMintOIDCToken(wf.workflow, job) {
+				continue
+			}
+			for _, step := range job.steps {
+				for _, identity := range stepOIDCIdentities(step) {
+					key := wf.file.repoID + job.environment + identity.id()
+					if _, ok := trusts[key]; !ok {
+						trusts[key] = &oidcTrust{
+							repoID:      wf.file.repoID,
+							environment: job.environment,
+							identityID:  identity.id(),
+							refs:        map[string]bool{},
+							subjects:    map[string]bool{},
+						}
+					}
+					trusts[key].refs[wf.file.ref] = true
+					for _, subject := range oidcSubjects(wf, job, claimKeys[wf.file.repoID], e.organization) {
+						trusts[key].subjects[subject] = true
+					}
+				}
+			}
+		}
+	}
+
+	e.insertOIDCTrusts(trusts)
+}
+
+// Returns the subject claim keys in use for each repository, taking into account organization and
+// repository customizations.
+func (e *Enricher) getOIDCSubClaimKeys() map[string][]string {
+	records := e.db.Run(`
+	MATCH (r:Repository)-[:OWNED_BY]->(o:Organization{login: $organization})
+	RETURN r.id as repoID, r.oidcUseDefault as useDefault, r.oidcSubClaimKeys as repoKeys,
+	o.oidcSubClaimKeys as orgKeys
+	`, map[string]interface{}{"organization": e.organization})
+
+	claimKeys := map[string][]string{}
+	for records.Next() {
+		repoID := getString(records.Record().Get("repoID"))
+		useDefault, _ := records.Record().Get("useDefault")
+		repoKeys := getStrings(records.Record().Get("repoKeys"))
+		orgKeys := getStrings(records.Record().Get("orgKeys"))
+
+		switch {
+		case useDefault == false && len(repoKeys) > 0:
+			claimKeys[repoID] = repoKeys
+		case len(orgKeys) > 0:
+			claimKeys[repoID] = orgKeys
+		default:
+			claimKeys[repoID] = defaultOIDCSubClaimKeys
+		}
+	}
+	return claimKeys
+}
+
+// Returns true if the job is granted the id-token write permission, by itself or, if it doesn't set
+// any permissions, by its workflow.
+func canMintOIDCToken(w *workflow, job *workflowJob) bool {
+	permissions := job.permissions
+	if !job.hasPermissions {
+		permissions = w.permissions
+	}
+	return sliceContains(permissions, "id-token:write") || sliceContains(permissions, "write-all")
+}
+
+// Returns the cloud identities a step authenticates as with OIDC, rather than stored credentials.
+func stepOIDCIdentities(step *workflowStep) []cloudIdentity {
+	switch {
+	case strings.HasPrefix(step.uses, "aws-actions/configure-aws-credentials@"):
+		if step.with["aws-access-key-id"] != "" {
+			return []cloudIdentity{}
+		}
+	case strings.HasPrefix(step.uses, "google-github-actions/auth@"):
+		if step.with["workload_identity_provider"] == "" {
+			return []cloudIdentity{}
+		}
+	case strings.HasPrefix(step.uses, "azure/login@"):
+		if step.with["creds"] != "" {
+			return []cloudIdentity{}
+		}
+	}
+	return stepCloudIdentities(step)
+}
+
+// Returns the subject claims of the tokens a job can mint, for each of its workflow's triggers.
+// Claims we can't predict (e.g. the actor) are replaced with a "*" wildcard.
+func oidcSubjects(wf workflowFile, job *workflowJob, claimKeys []string, organization string) []string {
+	repo := organization + "/" + wf.file.repoName
+	ref := "refs/heads/" + wf.file.ref
+
+	subjects := []string{}
+	for _, trigger := range wf.workflow.triggers {
+		context := "ref:" + ref
+		switch {
+		case job.environment != "":
+			context = "environment:" + job.environment
+		case trigger == "pull_request":
+			context = "pull_request"
+		}
+
+		claims := []string{}
+		for _, key := range claimKeys {
+			switch key {
+			case "context":
+				claims = append(claims, context)
+			case "repo":
+				claims = append(claims, "repo:"+repo)
+			case "repository_owner":
+				claims = append(claims, "repository_owner:"+organization)
+			case "environment":
+				claims = append(claims, "environment:"+job.environment)
+			case "ref":
+				claims = append(claims, "ref:"+ref)
+			case "event_name":
+				claims = append(claims, "event_name:"+trigger)
+			case "workflow":
+				claims = append(claims, "workflow:"+wf.workflow.name)
+			case "job_workflow_ref":
+				claims = append(claims, "job_workflow_ref:"+repo+"/"+wf.file.path+"@"+ref)
+			default:
+				claims = append(claims, key+":*")
+			}
+		}
+		subjects = append(subjects, strings.Join(claims, ":"))
+	}
+	return subjects
+}
+
+// Creates the trust relationships, derived from the repositories of the workflows, see
+// deleteDerivedData.
+func (e *Enricher) insertOIDCTrusts(trusts map[string]*oidcTrust) {
+	repoRows := []map[string]interface{}{}
+	environmentRows := []map[string]interface{}{}
+
+	for _, trust := range trusts {
+		row := map[string]interface{}{
+			"repoID":      trust.repoID,
+			"environment": trust.environment,
+			"identityID":  trust.identityID,
+			"refs":        sortedKeys(trust.refs),
+			"subjects":    sortedKeys(trust.subjects),
+		}
+		if trust.environment == "" {
+			repoRows = append(repoRows, row)
+		} else {
+			environmentRows = append(environmentRows, row)
+		}
+	}
+
+	e.runBatched(`
+	UNWIND $trusts AS trust
+
+	MATCH (r:Repository{id: trust.repoID})
+	MATCH (c:CloudIdentity{id: trust.identityID})
+	MERGE (r)-[rel:CAN_ASSUME_VIA_OIDC]->(c)
+	SET rel.refs = trust.refs,
+	rel.subjects = trust.subjects,
+	rel.enrichedFrom = trust.repoID,
+	rel.session = $session
+	`, "trusts", repoRows)
+
+	e.runBatched(`
+	UNWIND $trusts AS trust
+
+	MATCH (:Repository{id: trust.repoID})-[:HAS_ENVIRONMENT]->(e:Environment{name: trust.environment})
+	MATCH (c:CloudIdentity{id: trust.identityID})
+	MERGE (e)-[rel:CAN_ASSUME_VIA_OIDC]->(c)
+	SET rel.refs = trust.refs,
+	rel.subjects = trust.subjects,
+	rel.enrichedFrom = trust.repoID,
+	rel.session = $session
+	`, "trusts", environmentRows)
+}
+
+// Returns the keys of a set, sorted.
+func sortedKeys(set map[string]bool) []string {
+	keys := []string{}
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
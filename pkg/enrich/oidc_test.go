@@ -0,0 +1,120 @@
+package enrich
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOIDCSubjects(t *testing.T) {
+	file := ciFile{path: ".github/workflows/deploy.yml", ref: "main", repoName: "api"}
+
+	testCases := []struct {
+		name        string
+		triggers    []string
+		environment string
+		claimKeys   []string
+		want        []string
+	}{
+		{
+			name:      "default claim on a branch",
+			triggers:  []string{"push"},
+			claimKeys: defaultOIDCSubClaimKeys,
+			want:      []string{"repo:octo-org/api:ref:refs/heads/main"},
+		},
+		{
+			name:        "default claim in an environment",
+			triggers:    []string{"push", "workflow_dispatch"},
+			environment: "production",
+			claimKeys:   defaultOIDCSubClaimKeys,
+			want: []string{
+				"repo:octo-org/api:environment:production",
+				"repo:octo-org/api:environment:production",
+			},
+		},
+		{
+			name:      "default claim on a pull request",
+			triggers:  []string{"pull_request", "push"},
+			claimKeys: defaultOIDCSubClaimKeys,
+			want: []string{
+				"repo:octo-org/api:pull_request",
+				"repo:octo-org/api:ref:refs/heads/main",
+			},
+		},
+		{
+			name:        "custom claim",
+			triggers:    []string{"push"},
+			environment: "production",
+			claimKeys:   []string{"repository_owner", "environment", "event_name", "workflow", "job_workflow_ref"},
+			want: []string{
+				"repository_owner:octo-org:environment:production:event_name:push:workflow:Deploy:" +
+					"job_workflow_ref:octo-org/api/.github/workflows/deploy.yml@refs/heads/main",
+			},
+		},
+		{
+			name:      "custom claim with ref and unpredictable claims",
+			triggers:  []string{"push"},
+			claimKeys: []string{"repo", "ref", "actor"},
+			want:      []string{"repo:octo-org/api:ref:refs/heads/main:actor:*"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			wf := workflowFile{file: file, workflow: &workflow{name: "Deploy", triggers: tc.triggers}}
+			job := &workflowJob{environment: tc.environment}
+			got := oidcSubjects(wf, job, tc.claimKeys, "octo-org")
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("oidcSubjects() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCanMintOIDCToken(t *testing.T) {
+	testCases := []struct {
+		workflowPermissions []string
+		jobPermissions      []string
+		want                bool
+	}{
+		{workflowPermissions: []string{"id-token:write"}, want: true},
+		{workflowPermissions: []string{"write-all"}, want: true},
+		{workflowPermissions: []string{"id-token:write"}, jobPermissions: []string{"contents:read"}, want: false},
+		{jobPermissions: []string{"id-token:write", "contents:read"}, want: true},
+		{workflowPermissions: []string{"read-all"}, want: false},
+		// an empty permissions map grants nothing, rather than falling back to the workflow's
+		{workflowPermissions: []string{"id-token:write"}, jobPermissions: []string{}, want: false},
+	}
+
+	for _, tc := range testCases {
+		w := &workflow{permissions: tc.workflowPermissions}
+		job := &workflowJob{permissions: tc.jobPermissions, hasPermissions: tc.jobPermissions != nil}
+		if got := canMintOIDCToken(w, job); got != tc.want {
+			t.Errorf("canMintOIDCToken(%v, %v) = %v, want %v", tc.workflowPermissions, tc.jobPermissions, got, tc.want)
+		}
+	}
+}
+
+func TestCanMintOIDCTokenEmptyJobPermissions(t *testing.T) {
+	w, err := parseWorkflow(`
+on: push
+permissions:
+  id-token: write
+jobs:
+  inherit:
+    runs-on: ubuntu-latest
+  none:
+    runs-on: ubuntu-latest
+    permissions: {}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]bool{}
+	for _, job := range w.jobs {
+		got[job.key] = canMintOIDCToken(w, job)
+	}
+	if want := map[string]bool{"inherit": true, "none": false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("canMintOIDCToken() = %v, want %v", got, want)
+	}
+}
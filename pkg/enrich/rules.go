@@ -0,0 +1,238 @@
+package enrich
+
+import (
+	"crypto/md5"
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ovotech/gitoops/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// The rules we apply when none are given, which tag files mentioning well-known services and
+// extract candidate environment variable names.
+//
+//go:embed default_rules.yaml
+var defaultRulesData []byte
+
+// Matches valid Neo4j labels and property names, since these are interpolated into queries.
+var identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Properties that set rules can't overwrite: identifiers, what we match rules against and what
+// ingestors and enrichments rely on.
+var reservedProperties = []string{
+	"id",
+	"session",
+	"path",
+	"ref",
+	"text",
+	"textHash",
+	"tags",
+	"enrichedFrom",
+	"enrichedAt",
+	"enrichedRulesHash",
+	"enrichedFileIDs",
+	"enrichedTextHash",
+	"runsUntrustedCodeWithSecrets",
+	"scriptInjectionExpressions",
+	"scriptInjectionLines",
+	"undefinedEnvironmentVariables",
+}
+
+// Supported rule match types.
+const (
+	matchSubstring = "substring"
+	matchRegex     = "regex"
+	matchYAMLPath  = "yamlpath"
+)
+
+// A set of enrichment rules, as loaded from a YAML or JSON rules file.
+type Rules struct {
+	Version string  `yaml:"version"`
+	Rules   []*Rule `yaml:"rules"`
+
+	// an MD5 hash of the rules file, so files are enriched again whenever the rules change
+	hash string
+}
+
+// A rule matches the value of a node property and either adds a tag to the node's `tags` or sets a
+// property to the list of matched values.
+type Rule struct {
+	ID    string `yaml:"id"`
+	Label string `yaml:"label"`
+	// the property to match against, defaults to "text"
+	Property string `yaml:"property"`
+	// one of "substring", "regex" or "yamlpath"
+	Match      string   `yaml:"match"`
+	Patterns   []string `yaml:"patterns"`
+	IgnoreCase bool     `yaml:"ignoreCase"`
+	// for yamlpath rules, a dot-separated path where "*" matches any key or item, e.g.
+	// "jobs.*.steps.*.uses". Patterns are then optional regular expressions filtering the values.
+	Path string `yaml:"path"`
+	Tag  string `yaml:"tag"`
+	Set  string `yaml:"set"`
+
+	regexes []*regexp.Regexp
+}
+
+// Returns the default rules shipped with GitOops.
+func DefaultRules() *Rules {
+	rules, err := parseRules(defaultRulesData)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// Loads rules from a YAML or JSON rules file.
+func LoadRules(rulesPath string) (*Rules, error) {
+	data, err := os.ReadFile(rulesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := parseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", rulesPath, err)
+	}
+	return rules, nil
+}
+
+func parseRules(data []byte) (*Rules, error) {
+	rules := &Rules{hash: fmt.Sprintf("%x", md5.Sum(data))}
+	if err := yaml.Unmarshal(data, rules); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range rules.Rules {
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+		}
+	}
+	return rules, nil
+}
+
+// Validates the rule, sets defaults and compiles its patterns.
+func (r *Rule) compile() error {
+	if r.Property == "" {
+		r.Property = "text"
+	}
+	for _, identifier := range []string{r.Label, r.Property} {
+		if !identifierRegex.MatchString(identifier) {
+			return fmt.Errorf("invalid label or property name '%s'", identifier)
+		}
+	}
+	if (r.Tag == "") == (r.Set == "") {
+		return fmt.Errorf("exactly one of tag or set is required")
+	}
+	if r.Set != "" && !identifierRegex.MatchString(r.Set) {
+		return fmt.Errorf("invalid property name '%s'", r.Set)
+	}
+	if r.Set != "" && (sliceContains(reservedProperties, r.Set) || r.Set == r.Property) {
+		return fmt.Errorf("property '%s' is reserved and can't be set", r.Set)
+	}
+
+	switch r.Match {
+	case matchSubstring:
+		if r.IgnoreCase {
+			for i, pattern := range r.Patterns {
+				r.Patterns[i] = strings.ToLower(pattern)
+			}
+		}
+	case matchRegex, matchYAMLPath:
+		if r.Match == matchYAMLPath && r.Path == "" {
+			return fmt.Errorf("yamlpath rules require a path")
+		}
+		for _, pattern := range r.Patterns {
+			if r.IgnoreCase {
+				pattern = "(?i)" + pattern
+			}
+			regex, err := regexp.Compile(pattern)
+			if err != nil {
+				return err
+			}
+			r.regexes = append(r.regexes, regex)
+		}
+	default:
+		return fmt.Errorf("unknown match type '%s'", r.Match)
+	}
+
+	return nil
+}
+
+// Returns the unique values matched by the rule in value.
+func (r *Rule) matches(value string) []string {
+	matches := []string{}
+
+	switch r.Match {
+	case matchSubstring:
+		if r.IgnoreCase {
+			value = strings.ToLower(value)
+		}
+		for _, pattern := range r.Patterns {
+			if strings.Contains(value, pattern) {
+				matches = append(matches, pattern)
+			}
+		}
+	case matchRegex:
+		for _, regex := range r.regexes {
+			matches = append(matches, regex.FindAllString(value, -1)...)
+		}
+	case matchYAMLPath:
+		root := yaml.Node{}
+		if err := yaml.Unmarshal([]byte(value), &root); err != nil || len(root.Content) == 0 {
+			return matches
+		}
+		for _, node := range yamlPathNodes(root.Content[0], strings.Split(r.Path, ".")) {
+			for _, v := range scalarValues(node) {
+				if len(r.regexes) == 0 {
+					matches = append(matches, v)
+				}
+				for _, regex := range r.regexes {
+					if regex.MatchString(v) {
+						matches = append(matches, v)
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return utils.RemoveDuplicateStrings(matches)
+}
+
+// Returns the nodes found at path under node. A "*" path segment matches any mapping value or
+// sequence item.
+func yamlPathNodes(node *yaml.Node, path []string) []*yaml.Node {
+	if node == nil {
+		return []*yaml.Node{}
+	}
+	if len(path) == 0 {
+		return []*yaml.Node{node}
+	}
+
+	children := []*yaml.Node{}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if path[0] == "*" || node.Content[i].Value == path[0] {
+				children = append(children, node.Content[i+1])
+			}
+		}
+	case yaml.SequenceNode:
+		if path[0] == "*" {
+			children = node.Content
+		}
+	case yaml.AliasNode:
+		return yamlPathNodes(node.Alias, path)
+	}
+
+	nodes := []*yaml.Node{}
+	for _, child := range children {
+		nodes = append(nodes, yamlPathNodes(child, path[1:])...)
+	}
+	return nodes
+}
@@ -0,0 +1,271 @@
+package enrich
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := parseRules([]byte(`
+version: "2"
+rules:
+  - id: tag-docker
+    label: File
+    match: substring
+    ignoreCase: true
+    patterns: ["Docker"]
+    tag: docker
+  - id: extract-actions
+    label: File
+    match: yamlpath
+    path: jobs.*.steps.*.uses
+    set: actions
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.Version != "2" || len(rules.Rules) != 2 || rules.hash == "" {
+		t.Fatalf("parseRules() = %+v", rules)
+	}
+	if rule := rules.Rules[0]; rule.Property != "text" || !reflect.DeepEqual(rule.Patterns, []string{"docker"}) {
+		t.Errorf("parseRules() rule = %+v, want text property and lowercase patterns", rule)
+	}
+
+	if _, err := parseRules([]byte("rules: [")); err == nil {
+		t.Error("parseRules() of invalid YAML should fail")
+	}
+	_, err = parseRules([]byte("rules:\n  - id: broken\n    label: File\n    match: fuzzy\n    tag: x\n"))
+	if err == nil || !strings.Contains(err.Error(), "rule broken") {
+		t.Errorf("parseRules() error = %v, want the failing rule's ID", err)
+	}
+
+	if rules := DefaultRules(); len(rules.Rules) == 0 {
+		t.Error("DefaultRules() is empty")
+	}
+}
+
+func TestRulesHash(t *testing.T) {
+	rules := `
+version: "1"
+rules:
+  - id: tag-docker
+    label: File
+    match: substring
+    patterns: ["docker"]
+    tag: docker
+`
+	original, _ := parseRules([]byte(rules))
+	same, _ := parseRules([]byte(rules))
+	// editing the rules without bumping their version still changes their hash
+	edited, _ := parseRules([]byte(strings.Replace(rules, `["docker"]`, `["docker", "podman"]`, 1)))
+
+	if original.hash != same.hash {
+		t.Errorf("hashes of the same rules differ: %s, %s", original.hash, same.hash)
+	}
+	if original.hash == edited.hash {
+		t.Errorf("hashes of edited rules are the same: %s", original.hash)
+	}
+}
+
+func TestRuleCompile(t *testing.T) {
+	testCases := []struct {
+		name    string
+		rule    Rule
+		wantErr string
+	}{
+		{
+			name: "valid tag rule",
+			rule: Rule{Label: "File", Match: matchSubstring, Patterns: []string{"x"}, Tag: "x"},
+		},
+		{
+			name: "valid set rule",
+			rule: Rule{Label: "Repository", Property: "description", Match: matchRegex, Patterns: []string{"[a-z]+"}, Set: "words"},
+		},
+		{
+			name:    "invalid label",
+			rule:    Rule{Label: "File) DETACH DELETE (n", Match: matchSubstring, Tag: "x"},
+			wantErr: "invalid label or property name",
+		},
+		{
+			name:    "invalid property",
+			rule:    Rule{Label: "File", Property: "text.length", Match: matchSubstring, Tag: "x"},
+			wantErr: "invalid label or property name",
+		},
+		{
+			name:    "tag and set",
+			rule:    Rule{Label: "File", Match: matchSubstring, Tag: "x", Set: "y"},
+			wantErr: "exactly one of tag or set",
+		},
+		{
+			name:    "neither tag nor set",
+			rule:    Rule{Label: "File", Match: matchSubstring},
+			wantErr: "exactly one of tag or set",
+		},
+		{
+			name:    "invalid set property",
+			rule:    Rule{Label: "File", Match: matchSubstring, Set: "a b"},
+			wantErr: "invalid property name",
+		},
+		{
+			name:    "matched property",
+			rule:    Rule{Label: "Repository", Property: "description", Match: matchSubstring, Set: "description"},
+			wantErr: "reserved",
+		},
+		{
+			name:    "unknown match",
+			rule:    Rule{Label: "File", Match: "glob", Tag: "x"},
+			wantErr: "unknown match type",
+		},
+		{
+			name:    "yamlpath without path",
+			rule:    Rule{Label: "File", Match: matchYAMLPath, Set: "values"},
+			wantErr: "require a path",
+		},
+		{
+			name:    "invalid regex",
+			rule:    Rule{Label: "File", Match: matchRegex, Patterns: []string{"("}, Tag: "x"},
+			wantErr: "missing closing )",
+		},
+	}
+	for _, property := range reservedProperties {
+		testCases = append(testCases, struct {
+			name    string
+			rule    Rule
+			wantErr string
+		}{
+			name:    "reserved " + property,
+			rule:    Rule{Label: "File", Property: "path", Match: matchSubstring, Set: property},
+			wantErr: "reserved",
+		})
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rule.compile()
+			if tc.wantErr == "" && err != nil {
+				t.Errorf("compile() = %v, want no error", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("compile() = %v, want an error containing '%s'", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	workflow := `
+jobs:
+  build:
+    steps:
+      - uses: actions/checkout@v3
+      - uses: octo-org/deploy@v1
+      - run: make
+  test:
+    steps:
+      - uses: actions/checkout@v3
+`
+
+	testCases := []struct {
+		name  string
+		rule  Rule
+		value string
+		want  []string
+	}{
+		{
+			name:  "substring",
+			rule:  Rule{Match: matchSubstring, Patterns: []string{"docker", "Docker", "kubectl"}},
+			value: "docker build && docker push",
+			want:  []string{"docker"},
+		},
+		{
+			name:  "substring ignoring case",
+			rule:  Rule{Match: matchSubstring, Patterns: []string{"Docker"}, IgnoreCase: true},
+			value: "DOCKER build",
+			want:  []string{"docker"},
+		},
+		{
+			name:  "regex",
+			rule:  Rule{Match: matchRegex, Patterns: []string{`\$[A-Z_]+`}},
+			value: "echo $TOKEN $HOME $TOKEN",
+			want:  []string{"$TOKEN", "$HOME"},
+		},
+		{
+			name:  "yamlpath",
+			rule:  Rule{Match: matchYAMLPath, Path: "jobs.*.steps.*.uses"},
+			value: workflow,
+			want:  []string{"actions/checkout@v3", "octo-org/deploy@v1"},
+		},
+		{
+			name:  "yamlpath with patterns",
+			rule:  Rule{Match: matchYAMLPath, Path: "jobs.*.steps.*.uses", Patterns: []string{"^octo-org/"}},
+			value: workflow,
+			want:  []string{"octo-org/deploy@v1"},
+		},
+		{
+			name:  "yamlpath on invalid YAML",
+			rule:  Rule{Match: matchYAMLPath, Path: "jobs"},
+			value: "jobs: [",
+			want:  []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.rule.Label = "File"
+			tc.rule.Tag = "x"
+			if err := tc.rule.compile(); err != nil {
+				t.Fatal(err)
+			}
+			if got := tc.rule.matches(tc.value); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("matches() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestYAMLPathNodes(t *testing.T) {
+	root := yaml.Node{}
+	err := yaml.Unmarshal([]byte(`
+defaults: &defaults
+  image: golang
+jobs:
+  - name: build
+    image: node
+  - name: test
+    <<: *defaults
+matrix:
+  - [a, b]
+  - [c]
+`), &root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := root.Content[0]
+
+	testCases := []struct {
+		path string
+		want []string
+	}{
+		{path: "jobs.*.name", want: []string{"build", "test"}},
+		{path: "jobs.*.image", want: []string{"node"}},
+		{path: "jobs.0.name", want: []string{}},
+		{path: "matrix.*.*", want: []string{"a", "b", "c"}},
+		{path: "defaults.image", want: []string{"golang"}},
+		{path: "missing.*", want: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			got := []string{}
+			for _, node := range yamlPathNodes(doc, strings.Split(tc.path, ".")) {
+				got = append(got, scalarValues(node)...)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("yamlPathNodes(%s) = %v, want %v", tc.path, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package enrich
+
+import (
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// Matches workflow expressions, e.g. ${{ github.event.issue.title }}.
+	expressionRegex = regexp.MustCompile(`\$\{\{(.*?)\}\}`)
+
+	// Matches contexts holding values controlled by whoever opens an issue, pull request, comment,
+	// commit etc. See:
+	// https://securitylab.github.com/research/github-actions-untrusted-input/
+	taintedContextRegex = regexp.MustCompile(`github\.head_ref|github\.event\.(` + strings.Join([]string{
+		`issue\.(title|body)`,
+		`pull_request\.(title|body)`,
+		`pull_request\.head\.(ref|label)`,
+		`pull_request\.head\.repo\.default_branch`,
+		`comment\.body`,
+		`review\.body`,
+		`review_comment\.body`,
+		`discussion\.(title|body)`,
+		`pages\.[^.]+\.page_name`,
+		`commits\.[^.]+\.(message|author\.(email|name))`,
+		`head_commit\.(message|author\.(email|name))`,
+		`workflow_run\.(head_branch|display_title)`,
+		`workflow_run\.head_commit\.(message|author\.(email|name))`,
+		`workflow_run\.pull_requests\.[^.]+\.head\.ref`,
+	}, "|") + `)`)
+)
+
+// An attacker-controlled expression interpolated into a script.
+type scriptInjection struct {
+	expression string
+	line       int
+}
+
+// Flags workflow files and steps that interpolate attacker-controlled expressions into `run`
+// scripts or actions/github-script scripts, allowing command injection.
+func (e *Enricher) enrichScriptInjections() {
+	workflows := e.getWorkflows()
+	files := []map[string]interface{}{}
+	steps := []map[string]interface{}{}
+
+	for _, wf := range workflows {
+		fileExpressions := []string{}
+		fileLines := []int{}
+		sourceLines := strings.Split(wf.file.text, "\n")
+
+		for _, job := range wf.workflow.jobs {
+			for _, step := range job.steps {
+				expressions := []string{}
+				lines := []int{}
+				for _, injection := range findScriptInjections(step, sourceLines) {
+					expressions = append(expressions, injection.expression)
+					lines = append(lines, injection.line)
+				}
+				fileExpressions = append(fileExpressions, expressions...)
+				fileLines = append(fileLines, lines...)
+
+				steps = append(steps, map[string]interface{}{
+					"id":          workflowStepID(wf.file.id, job, step),
+					"expressions": expressions,
+					"lines":       lines,
+				})
+			}
+		}
+
+		files = append(files, map[string]interface{}{
+			"id":          wf.file.id,
+			"expressions": fileExpressions,
+			"lines":       fileLines,
+		})
+	}
+
+	e.runBatched(`
+	UNWIND $files AS file
+	MATCH (f:File{id: file.id})
+	SET f.scriptInjectionExpressions = file.expressions,
+	f.scriptInjectionLines = file.lines
+	`, "files", files)
+
+	e.runBatched(`
+	UNWIND $steps AS step
+	MATCH (s:Step{id: step.id})
+	SET s.scriptInjectionExpressions = step.expressions,
+	s.scriptInjectionLines = step.lines
+	`, "steps", steps)
+}
+
+// Returns the tainted expressions in a step's script along with the line they're on in the file's
+// sourceLines. Lines can't be derived from the decoded script, as folded scalars join lines and
+// quoted scalars may contain escapes, so we look for the expressions in the source from where the
+// script starts.
+func findScriptInjections(step *workflowStep, sourceLines []string) []scriptInjection {
+	script := mappingValue(step.node, "run")
+	if strings.HasPrefix(step.uses, "actions/github-script@") {
+		script = mappingValue(mappingValue(step.node, "with"), "script")
+	}
+	if script == nil || script.Kind != yaml.ScalarNode {
+		return []scriptInjection{}
+	}
+
+	// the position in the source after the last expression we found
+	line, column := script.Line-1, script.Column-1
+
+	injections := []scriptInjection{}
+	for _, loc := range expressionRegex.FindAllStringSubmatchIndex(script.Value, -1) {
+		expression := script.Value[loc[0]:loc[1]]
+		if !taintedContextRegex.MatchString(script.Value[loc[2]:loc[3]]) {
+			continue
+		}
+		injection := scriptInjection{expression: expression, line: script.Line}
+		if found := findSourceLine(sourceLines, expression, &line, &column); found > 0 {
+			injection.line = found
+		}
+		injections = append(injections, injection)
+	}
+	return injections
+}
+
+// Returns the 1-based line of the first occurrence of text in sourceLines at or after the 0-based
+// line and column, which are moved past it, or 0 if there's none.
+func findSourceLine(sourceLines []string, text string, line, column *int) int {
+	for i := *line; i >= 0 && i < len(sourceLines); i++ {
+		start := 0
+		if i == *line && *column <= len(sourceLines[i]) {
+			start = *column
+		}
+		if j := strings.Index(sourceLines[i][start:], text); j >= 0 {
+			*line, *column = i, start+j+len(text)
+			return i + 1
+		}
+	}
+	return 0
+}
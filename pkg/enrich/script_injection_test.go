@@ -0,0 +1,110 @@
+package enrich
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindScriptInjections(t *testing.T) {
+	text := `on: issues
+jobs:
+  triage:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ github.event.issue.title }}"
+      - run: |
+          echo "triaging"
+          echo "${{ github.event.issue.body }}" | ./triage.sh
+          echo "${{ github.event.issue.number }}"
+      - run: >
+          echo
+          "${{ github.event.comment.body }}"
+          "${{ github.event.issue.title }}"
+      - run: "echo \"escaped\"\n\necho ${{ github.head_ref }}"
+      - uses: actions/github-script@v6
+        with:
+          script: |
+            console.log("safe")
+            console.log("${{ github.event.pull_request.title }}")
+      - uses: actions/checkout@v3
+        with:
+          ref: ${{ github.head_ref }}
+`
+	w, err := parseWorkflow(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceLines := strings.Split(text, "\n")
+
+	testCases := []struct {
+		name string
+		want []scriptInjection
+	}{
+		{
+			name: "plain scalar",
+			want: []scriptInjection{{"${{ github.event.issue.title }}", 6}},
+		},
+		{
+			name: "literal block",
+			want: []scriptInjection{{"${{ github.event.issue.body }}", 9}},
+		},
+		{
+			name: "folded block",
+			want: []scriptInjection{
+				{"${{ github.event.comment.body }}", 13},
+				{"${{ github.event.issue.title }}", 14},
+			},
+		},
+		{
+			name: "double quoted scalar with escapes",
+			want: []scriptInjection{{"${{ github.head_ref }}", 15}},
+		},
+		{
+			name: "github-script",
+			want: []scriptInjection{{"${{ github.event.pull_request.title }}", 20}},
+		},
+		{
+			name: "not a script",
+			want: []scriptInjection{},
+		},
+	}
+
+	steps := w.jobs[0].steps
+	if len(steps) != len(testCases) {
+		t.Fatalf("got %d steps, want %d", len(steps), len(testCases))
+	}
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := findScriptInjections(steps[i], sourceLines)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("findScriptInjections() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFindScriptInjectionsRepeatedExpression(t *testing.T) {
+	text := `on: issues
+jobs:
+  triage:
+    runs-on: ubuntu-latest
+    steps:
+      - run: |
+          echo "${{ github.event.issue.title }}"
+          echo "${{ github.event.issue.title }}" "${{ github.event.issue.title }}"
+`
+	w, err := parseWorkflow(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := findScriptInjections(w.jobs[0].steps[0], strings.Split(text, "\n"))
+	lines := []int{}
+	for _, injection := range got {
+		lines = append(lines, injection.line)
+	}
+	if !reflect.DeepEqual(lines, []int{7, 8, 8}) {
+		t.Errorf("lines = %v, want [7 8 8]", lines)
+	}
+}
@@ -0,0 +1,113 @@
+package enrich
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Triggers running workflows from the base repository, with access to its secrets, on events
+	// that external contributors can cause.
+	privilegedTriggers = []string{"pull_request_target", "workflow_run"}
+
+	// Matches expressions resolving to the untrusted code of a pull request or triggering workflow.
+	untrustedRefRegex = regexp.MustCompile(
+		`github\.event\.pull_request\.head\.|github\.event\.workflow_run\.head_|github\.head_ref`,
+	)
+	// Matches shell commands checking out a pull request.
+	untrustedCheckoutCommandRegex = regexp.MustCompile(`gh\s+pr\s+checkout|git\s+fetch\s+.*pull/`)
+)
+
+// Flags workflows that check out and run untrusted pull request code with access to secrets, and
+// links repositories to the secrets any external contributor can exfiltrate this way.
+func (e *Enricher) enrichUntrustedCodeWorkflows() {
+	workflows := e.getWorkflows()
+	e.tagUntrustedCodeWorkflows(workflows)
+	e.insertUntrustedCodeSecrets(workflows)
+}
+
+// Returns true if job checks out untrusted code in a privileged workflow and has access to secrets.
+func runsUntrustedCodeWithSecrets(w *workflow, job *workflowJob) bool {
+	privileged := false
+	for _, trigger := range w.triggers {
+		if sliceContains(privilegedTriggers, trigger) {
+			privileged = true
+		}
+	}
+	if !privileged || len(job.secrets) == 0 {
+		return false
+	}
+
+	for _, step := range job.steps {
+		if strings.HasPrefix(step.uses, "actions/checkout@") &&
+			untrustedRefRegex.MatchString(step.with["ref"]+" "+step.with["repository"]) {
+			return true
+		}
+		if untrustedCheckoutCommandRegex.MatchString(step.run) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Enricher) tagUntrustedCodeWorkflows(workflows []workflowFile) {
+	files := []map[string]interface{}{}
+	jobs := []map[string]interface{}{}
+
+	for _, wf := range workflows {
+		untrusted := false
+		for _, job := range wf.workflow.jobs {
+			jobUntrusted := runsUntrustedCodeWithSecrets(wf.workflow, job)
+			untrusted = untrusted || jobUntrusted
+			jobs = append(jobs, map[string]interface{}{
+				"id":        workflowJobID(wf.file.id, job),
+				"untrusted": jobUntrusted,
+			})
+		}
+		files = append(files, map[string]interface{}{
+			"id":        wf.file.id,
+			"untrusted": untrusted,
+		})
+	}
+
+	e.runBatched(`
+	UNWIND $files AS file
+	MATCH (f:File{id: file.id})
+	SET f.runsUntrustedCodeWithSecrets = file.untrusted
+	`, "files", files)
+
+	e.runBatched(`
+	UNWIND $jobs AS job
+	MATCH (j:Job{id: job.id})
+	SET j.runsUntrustedCodeWithSecrets = job.untrusted
+	`, "jobs", jobs)
+}
+
+// Links repositories to the secrets used by their jobs running untrusted code. This relies on the
+// USES_SECRET relationships from the workflows enrichment. Relationships from earlier runs were
+// deleted by deleteDerivedData, so fixed workflows stop showing as exploitable.
+func (e *Enricher) insertUntrustedCodeSecrets(workflows []workflowFile) {
+	rows := []map[string]interface{}{}
+
+	for _, wf := range workflows {
+		for _, job := range wf.workflow.jobs {
+			if !runsUntrustedCodeWithSecrets(wf.workflow, job) {
+				continue
+			}
+			rows = append(rows, map[string]interface{}{
+				"jobID":  workflowJobID(wf.file.id, job),
+				"repoID": wf.file.repoID,
+			})
+		}
+	}
+
+	e.runBatched(`
+	UNWIND $jobs AS job
+
+	MATCH (r:Repository{id: job.repoID})
+	MATCH (:Job{id: job.jobID})-[:USES_SECRET]->(v:EnvironmentVariable)
+	MERGE (r)-[rel:UNTRUSTED_CODE_RUNS_WITH]->(v)
+	SET rel.enrichedFrom = job.repoID,
+	rel.session = $session
+	`, "jobs", rows)
+}
@@ -0,0 +1,118 @@
+package enrich
+
+import "testing"
+
+func TestRunsUntrustedCodeWithSecrets(t *testing.T) {
+	testCases := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{
+			name: "pull_request_target checking out the head with secrets",
+			text: `
+on: pull_request_target
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v3
+        with:
+          ref: ${{ github.event.pull_request.head.sha }}
+      - run: npm test
+        env:
+          NPM_TOKEN: ${{ secrets.NPM_TOKEN }}
+`,
+			want: true,
+		},
+		{
+			name: "pull_request_target checking out the head repository with secrets",
+			text: `
+on: [pull_request_target]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v3
+        with:
+          repository: ${{ github.event.pull_request.head.repo.full_name }}
+          ref: ${{ github.head_ref }}
+      - run: make deploy TOKEN=${{ secrets.DEPLOY_TOKEN }}
+`,
+			want: true,
+		},
+		{
+			name: "pull_request_target checking out the head without secrets",
+			text: `
+on: pull_request_target
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v3
+        with:
+          ref: ${{ github.event.pull_request.head.sha }}
+      - run: npm test
+`,
+			want: false,
+		},
+		{
+			name: "pull_request_target checking out the base with secrets",
+			text: `
+on: pull_request_target
+jobs:
+  label:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v3
+      - run: ./label.sh
+        env:
+          TOKEN: ${{ secrets.LABEL_TOKEN }}
+`,
+			want: false,
+		},
+		{
+			name: "pull_request_target checking out with gh with secrets",
+			text: `
+on: pull_request_target
+env:
+  TOKEN: ${{ secrets.NPM_TOKEN }}
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: gh pr checkout ${{ github.event.number }} && npm test
+`,
+			want: true,
+		},
+		{
+			name: "pull_request checking out the head with secrets",
+			text: `
+on: pull_request
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v3
+        with:
+          ref: ${{ github.event.pull_request.head.sha }}
+      - run: npm test
+        env:
+          NPM_TOKEN: ${{ secrets.NPM_TOKEN }}
+`,
+			want: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := parseWorkflow(tc.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := runsUntrustedCodeWithSecrets(w, w.jobs[0]); got != tc.want {
+				t.Errorf("runsUntrustedCodeWithSecrets() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
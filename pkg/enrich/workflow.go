@@ -0,0 +1,276 @@
+package enrich
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// Match references to secrets and configuration variables in workflow expressions, e.g.
+	// ${{ secrets.AWS_SECRET_ACCESS_KEY }} or ${{ vars['REGISTRY'] }}.
+	secretRefRegex = regexp.MustCompile(
+		`\bsecrets(?:\.([A-Za-z_][A-Za-z0-9_-]*)|\[\s*['"]([A-Za-z_][A-Za-z0-9_-]*)['"]\s*\])`,
+	)
+	varRefRegex = regexp.MustCompile(
+		`\bvars(?:\.([A-Za-z_][A-Za-z0-9_-]*)|\[\s*['"]([A-Za-z_][A-Za-z0-9_-]*)['"]\s*\])`,
+	)
+)
+
+// A GitHub Actions workflow parsed from a workflow file. We only keep what we need to model the
+// workflow in the graph, along with the YAML nodes for more specific analysis.
+type workflow struct {
+	name        string
+	triggers    []string
+	permissions []string
+	jobs        []*workflowJob
+	node        *yaml.Node
+}
+
+type workflowJob struct {
+	key         string
+	name        string
+	line        int
+	runsOn      []string
+	environment string
+	permissions []string
+	// set if the job has a permissions key, even an empty one granting no permissions, in which
+	// case the workflow's permissions don't apply
+	hasPermissions bool
+	// set for jobs calling a reusable workflow
+	uses           string
+	secretsInherit bool
+	// secrets and configuration variables referenced by the job, including through workflow env
+	secrets []string
+	vars    []string
+	steps   []*workflowStep
+	node    *yaml.Node
+}
+
+type workflowStep struct {
+	index int
+	line  int
+	id    string
+	name  string
+	uses  string
+	run   string
+	with  map[string]string
+	node  *yaml.Node
+}
+
+// Parses the text of a GitHub Actions workflow file.
+func parseWorkflow(text string) (*workflow, error) {
+	root := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(text), &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("workflow is not a YAML mapping")
+	}
+	doc := root.Content[0]
+
+	w := &workflow{
+		name:        scalarValue(mappingValue(doc, "name")),
+		triggers:    mappingKeysOrValues(mappingValue(doc, "on")),
+		permissions: parsePermissions(mappingValue(doc, "permissions")),
+		node:        doc,
+	}
+
+	jobs := mappingValue(doc, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return w, nil
+	}
+	workflowEnv := mappingValue(doc, "env")
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		w.jobs = append(w.jobs, parseWorkflowJob(jobs.Content[i], jobs.Content[i+1], workflowEnv))
+	}
+
+	return w, nil
+}
+
+func parseWorkflowJob(keyNode, node, workflowEnv *yaml.Node) *workflowJob {
+	job := &workflowJob{
+		key:         keyNode.Value,
+		name:        scalarValue(mappingValue(node, "name")),
+		line:        keyNode.Line,
+		runsOn:      scalarValues(mappingValue(node, "runs-on")),
+		permissions: parsePermissions(mappingValue(node, "permissions")),
+		uses:        scalarValue(mappingValue(node, "uses")),
+		node:        node,
+	}
+	job.hasPermissions = mappingValue(node, "permissions") != nil
+
+	// environments are either a name or a mapping with a name and URL
+	environment := mappingValue(node, "environment")
+	if environment != nil && environment.Kind == yaml.MappingNode {
+		environment = mappingValue(environment, "name")
+	}
+	job.environment = scalarValue(environment)
+
+	job.secretsInherit = scalarValue(mappingValue(node, "secrets")) == "inherit"
+
+	// a job can read anything referenced in its own definition or in the workflow env
+	refs := append(scalarNodes(node), scalarNodes(workflowEnv)...)
+	job.secrets = expressionRefs(secretRefRegex, refs)
+	job.vars = expressionRefs(varRefRegex, refs)
+
+	steps := mappingValue(node, "steps")
+	if steps == nil || steps.Kind != yaml.SequenceNode {
+		return job
+	}
+	for i, stepNode := range steps.Content {
+		step := &workflowStep{
+			index: i,
+			line:  stepNode.Line,
+			id:    scalarValue(mappingValue(stepNode, "id")),
+			name:  scalarValue(mappingValue(stepNode, "name")),
+			uses:  scalarValue(mappingValue(stepNode, "uses")),
+			run:   scalarValue(mappingValue(stepNode, "run")),
+			with:  map[string]string{},
+			node:  stepNode,
+		}
+		with := mappingValue(stepNode, "with")
+		if with != nil && with.Kind == yaml.MappingNode {
+			for j := 0; j+1 < len(with.Content); j += 2 {
+				step.with[with.Content[j].Value] = scalarValue(with.Content[j+1])
+			}
+		}
+		job.steps = append(job.steps, step)
+	}
+
+	return job
+}
+
+// Returns permissions as a list of "scope:access" strings, or a single "read-all"/"write-all".
+func parsePermissions(node *yaml.Node) []string {
+	if node == nil {
+		return []string{}
+	}
+	if node.Kind == yaml.ScalarNode {
+		return []string{node.Value}
+	}
+
+	permissions := []string{}
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			permissions = append(permissions, node.Content[i].Value+":"+node.Content[i+1].Value)
+		}
+	}
+	return permissions
+}
+
+// Returns the unique names captured by regex in the values of nodes, sorted.
+func expressionRefs(regex *regexp.Regexp, nodes []*yaml.Node) []string {
+	seen := map[string]bool{}
+	for _, node := range nodes {
+		for _, match := range regex.FindAllStringSubmatch(node.Value, -1) {
+			name := match[1]
+			if name == "" {
+				name = match[2]
+			}
+			seen[name] = true
+		}
+	}
+
+	refs := []string{}
+	for name := range seen {
+		refs = append(refs, name)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// Returns the value node for key in a mapping node, or nil. Aliases and merge keys (`<<: *anchor`)
+// are resolved, as CircleCI configurations commonly use them.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			value := node.Content[i+1]
+			if value.Kind == yaml.AliasNode {
+				return value.Alias
+			}
+			return value
+		}
+	}
+
+	if key == "<<" {
+		return nil
+	}
+	merged := mappingValue(node, "<<")
+	if merged == nil {
+		return nil
+	}
+	if merged.Kind == yaml.SequenceNode {
+		for _, m := range merged.Content {
+			if value := mappingValue(m, key); value != nil {
+				return value
+			}
+		}
+		return nil
+	}
+	return mappingValue(merged, key)
+}
+
+// Returns the value of a scalar node, or an empty string.
+func scalarValue(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+// Returns the values of all scalars under node, excluding mapping keys.
+func scalarValues(node *yaml.Node) []string {
+	values := []string{}
+	for _, scalar := range scalarNodes(node) {
+		values = append(values, scalar.Value)
+	}
+	return values
+}
+
+// Returns the keys of a mapping node, or the values of a scalar or sequence node. This suits
+// fields like `on` that can take any of these forms.
+func mappingKeysOrValues(node *yaml.Node) []string {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return scalarValues(node)
+	}
+	keys := []string{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+	}
+	return keys
+}
+
+// Returns all scalar nodes under node, excluding mapping keys.
+func scalarNodes(node *yaml.Node) []*yaml.Node {
+	if node == nil {
+		return []*yaml.Node{}
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return []*yaml.Node{node}
+	case yaml.MappingNode:
+		scalars := []*yaml.Node{}
+		for i := 1; i < len(node.Content); i += 2 {
+			scalars = append(scalars, scalarNodes(node.Content[i])...)
+		}
+		return scalars
+	case yaml.AliasNode:
+		return scalarNodes(node.Alias)
+	default:
+		scalars := []*yaml.Node{}
+		for _, child := range node.Content {
+			scalars = append(scalars, scalarNodes(child)...)
+		}
+		return scalars
+	}
+}
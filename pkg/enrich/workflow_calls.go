@@ -0,0 +1,154 @@
+package enrich
+
+import (
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Cypher regular expression matching the paths of action metadata files.
+const actionPathRegex = `(.*/)?action\.ya?ml`
+
+// A call from a file to a reusable workflow or composite action defined in another file. The
+// called file is either in the caller's repository (repoID) or in another repository of the
+// organization (repoName).
+type workflowCall struct {
+	callerID       string
+	callerRepoID   string
+	repoID         string
+	repoName       string
+	paths          []string
+	ref            string
+	secretsInherit bool
+}
+
+// Links workflows and composite actions to the reusable workflows and composite actions they call,
+// across repositories of the organization. Write access to the called file's repository is
+// effectively code execution in the caller, with the secrets passed to it.
+func (e *Enricher) enrichWorkflowCalls() {
+	calls := []workflowCall{}
+
+	for _, wf := range e.getWorkflows() {
+		for _, job := range wf.workflow.jobs {
+			if call, ok := e.resolveWorkflowCall(wf.file, job.uses, false); ok {
+				call.secretsInherit = job.secretsInherit
+				calls = append(calls, call)
+			}
+			for _, step := range job.steps {
+				if call, ok := e.resolveWorkflowCall(wf.file, step.uses, true); ok {
+					calls = append(calls, call)
+				}
+			}
+		}
+	}
+
+	// composite actions can themselves use other composite actions
+	for _, file := range e.getFiles(actionPathRegex) {
+		for _, uses := range parseCompositeActionUses(file) {
+			if call, ok := e.resolveWorkflowCall(file, uses, true); ok {
+				calls = append(calls, call)
+			}
+		}
+	}
+
+	e.insertWorkflowCalls(calls)
+}
+
+// Resolves a `uses` value from a file to the files it may call. Calls to repositories outside of
+// the organization are ignored, those are covered by Action nodes.
+func (e *Enricher) resolveWorkflowCall(file ciFile, uses string, isAction bool) (workflowCall, bool) {
+	call := workflowCall{callerID: file.id, callerRepoID: file.repoID}
+
+	if strings.HasPrefix(uses, "./") {
+		call.repoID = file.repoID
+		call.ref = file.ref
+		call.paths = []string{path.Clean(uses)}
+	} else {
+		action, ok := parseActionRef(uses)
+		if !ok || !strings.EqualFold(action.owner, e.organization) {
+			return workflowCall{}, false
+		}
+		call.repoName = action.repo
+		call.ref = action.ref
+		call.paths = []string{path.Clean(action.path)}
+		if action.path == "" {
+			call.paths = []string{"."}
+		}
+	}
+
+	// actions are referenced by the directory holding their metadata file
+	if isAction {
+		dir := call.paths[0]
+		call.paths = []string{path.Join(dir, "action.yml"), path.Join(dir, "action.yaml")}
+	}
+
+	return call, true
+}
+
+// Returns the `uses` values of a composite action's steps.
+func parseCompositeActionUses(file ciFile) []string {
+	uses := []string{}
+
+	root := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(file.text), &root); err != nil {
+		log.Debugf("Skipping action %s (%s): %s", file.path, file.repoID, err)
+		return uses
+	}
+	if len(root.Content) == 0 {
+		return uses
+	}
+
+	steps := mappingValue(mappingValue(root.Content[0], "runs"), "steps")
+	if steps == nil {
+		return uses
+	}
+	for _, step := range steps.Content {
+		if u := scalarValue(mappingValue(step, "uses")); u != "" {
+			uses = append(uses, u)
+		}
+	}
+	return uses
+}
+
+// Creates the call relationships. Called files are looked up on the referenced branch, falling back
+// to the default branch when we haven't ingested that branch (e.g. for tags and commit SHAs). The
+// relationships are derived from the caller's repository, see deleteDerivedData.
+func (e *Enricher) insertWorkflowCalls(calls []workflowCall) {
+	rows := []map[string]interface{}{}
+
+	for _, call := range calls {
+		rows = append(rows, map[string]interface{}{
+			"callerID":       call.callerID,
+			"callerRepoID":   call.callerRepoID,
+			"repoID":         call.repoID,
+			"repoName":       call.repoName,
+			"paths":          call.paths,
+			"ref":            call.ref,
+			"secretsInherit": call.secretsInherit,
+		})
+	}
+
+	e.runBatched(`
+	UNWIND $calls AS call
+
+	MATCH (o:Organization)<-[:OWNED_BY]-(:Repository)
+	-[:HAS_CI_CONFIGURATION_FILE]->(caller:File{id: call.callerID})
+	MATCH (o)<-[:OWNED_BY]-(r:Repository)-[:HAS_CI_CONFIGURATION_FILE]->(target:File)
+	WHERE (r.id = call.repoID OR r.name = call.repoName)
+	AND target.path IN call.paths
+	AND (
+		target.ref = call.ref
+		OR (
+			target.ref = r.defaultBranch
+			AND NOT (r)-[:HAS_CI_CONFIGURATION_FILE]->(:File{path: target.path, ref: call.ref})
+		)
+	)
+
+	MERGE (caller)-[rel:CALLS_WORKFLOW]->(target)
+	SET rel.secretsInherit = call.secretsInherit,
+	rel.enrichedFrom = call.callerRepoID,
+	rel.session = $session
+	`, "calls", rows)
+}
@@ -0,0 +1,81 @@
+package enrich
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveWorkflowCall(t *testing.T) {
+	e := &Enricher{organization: "octo-org"}
+	file := ciFile{id: "f1", path: ".github/workflows/ci.yml", ref: "main", repoID: "r1"}
+
+	testCases := []struct {
+		uses     string
+		isAction bool
+		ok       bool
+		want     workflowCall
+	}{
+		{
+			uses: "./.github/workflows/build.yml",
+			ok:   true,
+			want: workflowCall{callerID: "f1", callerRepoID: "r1", repoID: "r1", ref: "main", paths: []string{".github/workflows/build.yml"}},
+		},
+		{
+			uses:     "./.github/actions/setup/",
+			isAction: true,
+			ok:       true,
+			want: workflowCall{callerID: "f1", callerRepoID: "r1", repoID: "r1", ref: "main", paths: []string{
+				".github/actions/setup/action.yml", ".github/actions/setup/action.yaml",
+			}},
+		},
+		{
+			uses: "Octo-Org/workflows/.github/workflows/deploy.yml@v1",
+			ok:   true,
+			want: workflowCall{callerID: "f1", callerRepoID: "r1", repoName: "workflows", ref: "v1", paths: []string{".github/workflows/deploy.yml"}},
+		},
+		{
+			uses:     "octo-org/setup-action@main",
+			isAction: true,
+			ok:       true,
+			want: workflowCall{callerID: "f1", callerRepoID: "r1", repoName: "setup-action", ref: "main", paths: []string{
+				"action.yml", "action.yaml",
+			}},
+		},
+		{uses: "actions/checkout@v3", isAction: true, ok: false},
+		{uses: "docker://alpine:3.8", isAction: true, ok: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.uses, func(t *testing.T) {
+			call, ok := e.resolveWorkflowCall(file, tc.uses, tc.isAction)
+			if ok != tc.ok {
+				t.Fatalf("resolveWorkflowCall() ok = %v, want %v", ok, tc.ok)
+			}
+			if ok && !reflect.DeepEqual(call, tc.want) {
+				t.Errorf("resolveWorkflowCall() = %+v, want %+v", call, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseCompositeActionUses(t *testing.T) {
+	file := ciFile{path: "action.yml", text: `
+name: setup
+runs:
+  using: composite
+  steps:
+    - uses: actions/setup-go@v4
+    - run: make
+      shell: bash
+    - uses: ./.github/actions/cache
+`}
+
+	want := []string{"actions/setup-go@v4", "./.github/actions/cache"}
+	if got := parseCompositeActionUses(file); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCompositeActionUses() = %v, want %v", got, want)
+	}
+
+	if got := parseCompositeActionUses(ciFile{text: "runs: ["}); len(got) != 0 {
+		t.Errorf("parseCompositeActionUses() of invalid YAML = %v, want none", got)
+	}
+}
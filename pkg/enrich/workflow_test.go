@@ -0,0 +1,151 @@
+package enrich
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testWorkflow = `
+name: Deploy
+on:
+  push:
+    branches: [main]
+  pull_request:
+permissions:
+  contents: read
+env:
+  REGISTRY: ${{ vars.REGISTRY }}
+  TOKEN: ${{ secrets.NPM_TOKEN }}
+jobs:
+  build:
+    runs-on: [self-hosted, linux]
+    steps:
+      - uses: actions/checkout@v3
+      - name: Build
+        id: build
+        run: make build
+        env:
+          AWS_KEY: ${{ secrets['AWS_SECRET_ACCESS_KEY'] }}
+  deploy:
+    name: Deploy to production
+    needs: build
+    runs-on: ubuntu-latest
+    permissions: write-all
+    environment:
+      name: production
+      url: https://fakenews.com
+    steps:
+      - uses: aws-actions/configure-aws-credentials@v2
+        with:
+          role-to-assume: arn:aws:iam::123456789012:role/deploy
+          aws-region: eu-west-1
+  release:
+    uses: fakenews/workflows/.github/workflows/release.yml@main
+    secrets: inherit
+`
+
+func TestParseWorkflow(t *testing.T) {
+	w, err := parseWorkflow(testWorkflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w.name != "Deploy" {
+		t.Errorf("name = %q", w.name)
+	}
+	if !reflect.DeepEqual(w.triggers, []string{"push", "pull_request"}) {
+		t.Errorf("triggers = %v", w.triggers)
+	}
+	if !reflect.DeepEqual(w.permissions, []string{"contents:read"}) {
+		t.Errorf("permissions = %v", w.permissions)
+	}
+	if len(w.jobs) != 3 {
+		t.Fatalf("got %d jobs, want 3", len(w.jobs))
+	}
+
+	build, deploy, release := w.jobs[0], w.jobs[1], w.jobs[2]
+
+	if build.key != "build" || build.line != 13 {
+		t.Errorf("build key, line = %q, %d", build.key, build.line)
+	}
+	if !reflect.DeepEqual(build.runsOn, []string{"self-hosted", "linux"}) {
+		t.Errorf("build runsOn = %v", build.runsOn)
+	}
+	// workflow env is readable by every job
+	if !reflect.DeepEqual(build.secrets, []string{"AWS_SECRET_ACCESS_KEY", "NPM_TOKEN"}) {
+		t.Errorf("build secrets = %v", build.secrets)
+	}
+	if !reflect.DeepEqual(build.vars, []string{"REGISTRY"}) {
+		t.Errorf("build vars = %v", build.vars)
+	}
+	if len(build.steps) != 2 {
+		t.Fatalf("got %d build steps, want 2", len(build.steps))
+	}
+	if step := build.steps[0]; step.index != 0 || step.uses != "actions/checkout@v3" || step.line != 16 {
+		t.Errorf("build step 0 = %+v", step)
+	}
+	if step := build.steps[1]; step.index != 1 || step.id != "build" || step.name != "Build" || step.run != "make build" {
+		t.Errorf("build step 1 = %+v", step)
+	}
+
+	if deploy.name != "Deploy to production" || deploy.environment != "production" {
+		t.Errorf("deploy name, environment = %q, %q", deploy.name, deploy.environment)
+	}
+	if !reflect.DeepEqual(deploy.permissions, []string{"write-all"}) {
+		t.Errorf("deploy permissions = %v", deploy.permissions)
+	}
+	if !reflect.DeepEqual(deploy.secrets, []string{"NPM_TOKEN"}) {
+		t.Errorf("deploy secrets = %v", deploy.secrets)
+	}
+	with := deploy.steps[0].with
+	if with["role-to-assume"] != "arn:aws:iam::123456789012:role/deploy" || with["aws-region"] != "eu-west-1" {
+		t.Errorf("deploy step with = %v", with)
+	}
+
+	if release.uses != "fakenews/workflows/.github/workflows/release.yml@main" || !release.secretsInherit {
+		t.Errorf("release uses, secretsInherit = %q, %v", release.uses, release.secretsInherit)
+	}
+	if len(release.steps) != 0 {
+		t.Errorf("release steps = %v", release.steps)
+	}
+}
+
+func TestParseWorkflowTriggers(t *testing.T) {
+	testCases := []struct {
+		text     string
+		triggers []string
+	}{
+		{"on: push\njobs: {}", []string{"push"}},
+		{"on: [push, pull_request_target]\njobs: {}", []string{"push", "pull_request_target"}},
+		{"on:\n  workflow_dispatch:\n  schedule:\n    - cron: '0 0 * * *'\n", []string{"workflow_dispatch", "schedule"}},
+		{"name: no triggers", []string{}},
+	}
+
+	for _, tc := range testCases {
+		w, err := parseWorkflow(tc.text)
+		if err != nil {
+			t.Fatalf("parseWorkflow(%q): %s", tc.text, err)
+		}
+		if !reflect.DeepEqual(w.triggers, tc.triggers) {
+			t.Errorf("parseWorkflow(%q) triggers = %v, want %v", tc.text, w.triggers, tc.triggers)
+		}
+	}
+}
+
+func TestParseWorkflowInvalid(t *testing.T) {
+	for _, text := range []string{"", "- a list", "jobs: [", "just a string"} {
+		if _, err := parseWorkflow(text); err == nil {
+			t.Errorf("parseWorkflow(%q) should fail", text)
+		}
+	}
+}
+
+func TestParseWorkflowIgnoresMalformedJobs(t *testing.T) {
+	w, err := parseWorkflow("on: push\njobs: not-a-mapping\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.jobs) != 0 {
+		t.Errorf("jobs = %v", w.jobs)
+	}
+}
@@ -0,0 +1,248 @@
+package enrich
+
+import (
+	"crypto/md5"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Cypher regular expression matching the paths of GitHub Actions workflow files.
+const workflowPathRegex = `\.github/workflows/[^/]+\.ya?ml`
+
+// A parsed workflow and the file it was defined in.
+type workflowFile struct {
+	file     ciFile
+	workflow *workflow
+}
+
+// Returns parsed workflows for all workflow files. Files that aren't valid workflows are skipped.
+func (e *Enricher) getWorkflows() []workflowFile {
+	workflows := []workflowFile{}
+	for _, file := range e.getFiles(workflowPathRegex) {
+		w, err := parseWorkflow(file.text)
+		if err != nil {
+			log.Debugf("Skipping workflow %s (%s): %s", file.path, file.repoID, err)
+			continue
+		}
+		workflows = append(workflows, workflowFile{file: file, workflow: w})
+	}
+	return workflows
+}
+
+// Returns the ID of a Job node. Job keys are unique per workflow.
+func workflowJobID(fileID string, job *workflowJob) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(fileID+job.key)))
+}
+
+// Returns the ID of a Step node.
+func workflowStepID(fileID string, job *workflowJob, step *workflowStep) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s%s%d", fileID, job.key, step.index))))
+}
+
+// Models GitHub Actions workflows as Workflow, Job and Step nodes, and links jobs to the secrets
+// they read and the environments they deploy to. The workflow model of the repositories we enrich
+// was deleted by deleteDerivedData, so jobs and steps removed from a workflow don't linger.
+func (e *Enricher) enrichWorkflows() {
+	workflows := e.getWorkflows()
+	e.insertWorkflows(workflows)
+	e.insertWorkflowJobs(workflows)
+	e.insertWorkflowSteps(workflows)
+	e.insertWorkflowJobsSecrets(workflows)
+	e.insertWorkflowJobsEnvironments(workflows)
+}
+
+func (e *Enricher) insertWorkflows(workflows []workflowFile) {
+	rows := []map[string]interface{}{}
+
+	for _, wf := range workflows {
+		rows = append(rows, map[string]interface{}{
+			"id":          wf.file.id,
+			"repoID":      wf.file.repoID,
+			"name":        wf.workflow.name,
+			"path":        wf.file.path,
+			"ref":         wf.file.ref,
+			"triggers":    wf.workflow.triggers,
+			"permissions": wf.workflow.permissions,
+		})
+	}
+
+	e.runBatched(`
+	UNWIND $workflows AS workflow
+
+	MERGE (w:Workflow{id: workflow.id})
+
+	SET w.name = workflow.name,
+	w.path = workflow.path,
+	w.ref = workflow.ref,
+	w.triggers = workflow.triggers,
+	w.permissions = workflow.permissions,
+	w.enrichedFrom = workflow.repoID,
+	w.session = $session
+
+	WITH w, workflow
+
+	MATCH (f:File{id: workflow.id})
+	MERGE (f)-[rel:DEFINES_WORKFLOW]->(w)
+	SET rel.enrichedFrom = workflow.repoID,
+	rel.session = $session
+	`, "workflows", rows)
+}
+
+func (e *Enricher) insertWorkflowJobs(workflows []workflowFile) {
+	rows := []map[string]interface{}{}
+
+	for _, wf := range workflows {
+		for _, job := range wf.workflow.jobs {
+			rows = append(rows, map[string]interface{}{
+				"id":             workflowJobID(wf.file.id, job),
+				"workflowID":     wf.file.id,
+				"repoID":         wf.file.repoID,
+				"key":            job.key,
+				"name":           job.name,
+				"line":           job.line,
+				"runsOn":         job.runsOn,
+				"environment":    job.environment,
+				"permissions":    job.permissions,
+				"uses":           job.uses,
+				"secretsInherit": job.secretsInherit,
+				"secrets":        job.secrets,
+				"vars":           job.vars,
+			})
+		}
+	}
+
+	e.runBatched(`
+	UNWIND $jobs AS job
+
+	MERGE (j:Job{id: job.id})
+
+	SET j.key = job.key,
+	j.name = job.name,
+	j.line = job.line,
+	j.runsOn = job.runsOn,
+	j.environment = job.environment,
+	j.permissions = job.permissions,
+	j.uses = job.uses,
+	j.secretsInherit = job.secretsInherit,
+	j.secrets = job.secrets,
+	j.vars = job.vars,
+	j.enrichedFrom = job.repoID,
+	j.session = $session
+
+	WITH j, job
+
+	MATCH (w:Workflow{id: job.workflowID})
+	MERGE (w)-[rel:HAS_JOB]->(j)
+	SET rel.enrichedFrom = job.repoID,
+	rel.session = $session
+	`, "jobs", rows)
+}
+
+func (e *Enricher) insertWorkflowSteps(workflows []workflowFile) {
+	rows := []map[string]interface{}{}
+
+	for _, wf := range workflows {
+		for _, job := range wf.workflow.jobs {
+			for _, step := range job.steps {
+				rows = append(rows, map[string]interface{}{
+					"id":     workflowStepID(wf.file.id, job, step),
+					"jobID":  workflowJobID(wf.file.id, job),
+					"repoID": wf.file.repoID,
+					"index":  step.index,
+					"line":   step.line,
+					"name":   step.name,
+					"uses":   step.uses,
+					"run":    step.run,
+				})
+			}
+		}
+	}
+
+	e.runBatched(`
+	UNWIND $steps AS step
+
+	MERGE (s:Step{id: step.id})
+
+	SET s.index = step.index,
+	s.line = step.line,
+	s.name = step.name,
+	s.uses = step.uses,
+	s.run = step.run,
+	s.enrichedFrom = step.repoID,
+	s.session = $session
+
+	WITH s, step
+
+	MATCH (j:Job{id: step.jobID})
+	MERGE (j)-[rel:HAS_STEP]->(s)
+	SET rel.enrichedFrom = step.repoID,
+	rel.session = $session
+	`, "steps", rows)
+}
+
+// Links jobs to the secrets they reference, as exposed to their repository (which includes
+// organization secrets) or to the environment they deploy to.
+func (e *Enricher) insertWorkflowJobsSecrets(workflows []workflowFile) {
+	rows := []map[string]interface{}{}
+
+	for _, wf := range workflows {
+		for _, job := range wf.workflow.jobs {
+			for _, secret := range job.secrets {
+				rows = append(rows, map[string]interface{}{
+					"jobID":       workflowJobID(wf.file.id, job),
+					"repoID":      wf.file.repoID,
+					"environment": job.environment,
+					"name":        secret,
+				})
+			}
+		}
+	}
+
+	e.runBatched(`
+	UNWIND $jobSecrets AS jobSecret
+
+	MATCH (j:Job{id: jobSecret.jobID})
+	MATCH (r:Repository{id: jobSecret.repoID})-[:EXPOSES_ENVIRONMENT_VARIABLE]->(v:EnvironmentVariable{name: jobSecret.name})
+	MERGE (j)-[rel:USES_SECRET]->(v)
+	SET rel.enrichedFrom = jobSecret.repoID,
+	rel.session = $session
+	`, "jobSecrets", rows)
+
+	e.runBatched(`
+	UNWIND $jobSecrets AS jobSecret
+
+	MATCH (j:Job{id: jobSecret.jobID})
+	MATCH (r:Repository{id: jobSecret.repoID})-[:HAS_ENVIRONMENT]->(:Environment{name: jobSecret.environment})-[:EXPOSES_ENVIRONMENT_VARIABLE]->(v:EnvironmentVariable{name: jobSecret.name})
+	MERGE (j)-[rel:USES_SECRET]->(v)
+	SET rel.enrichedFrom = jobSecret.repoID,
+	rel.session = $session
+	`, "jobSecrets", rows)
+}
+
+func (e *Enricher) insertWorkflowJobsEnvironments(workflows []workflowFile) {
+	rows := []map[string]interface{}{}
+
+	for _, wf := range workflows {
+		for _, job := range wf.workflow.jobs {
+			if job.environment == "" {
+				continue
+			}
+			rows = append(rows, map[string]interface{}{
+				"jobID":       workflowJobID(wf.file.id, job),
+				"repoID":      wf.file.repoID,
+				"environment": job.environment,
+			})
+		}
+	}
+
+	e.runBatched(`
+	UNWIND $jobEnvironments AS jobEnvironment
+
+	MATCH (j:Job{id: jobEnvironment.jobID})
+	MATCH (r:Repository{id: jobEnvironment.repoID})-[:HAS_ENVIRONMENT]->(e:Environment{name: jobEnvironment.environment})
+	MERGE (j)-[rel:DEPLOYS_TO]->(e)
+	SET rel.enrichedFrom = jobEnvironment.repoID,
+	rel.session = $session
+	`, "jobEnvironments", rows)
+}
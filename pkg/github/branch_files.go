@@ -0,0 +1,382 @@
+package github
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
+)
+
+// Ingests CI/CD configuration files from branches other than the default branch that may have
+// access to more secrets: protected branches and branches allowed by environments' deployment
+// branch policies. Files are linked to the branch protection rules and environments of the branch
+// they were found on.
+type BranchFilesIngestor struct {
+	gqlclient     *GraphQLClient
+	restclient    *RESTClient
+	db            *database.Database
+	data          *BranchFilesData
+	filePatterns  []string
+	repoName      string
+	repoURL       string
+	defaultBranch string
+	session       string
+}
+
+type BranchFilesData struct {
+	BranchProtectionRules struct {
+		Nodes []struct {
+			Pattern      string `json:"pattern"`
+			MatchingRefs struct {
+				TotalCount int `json:"totalCount"`
+				Nodes      []struct {
+					Name string `json:"name"`
+				} `json:"nodes"`
+			} `json:"matchingRefs"`
+		} `json:"nodes"`
+	}
+	Branches []struct {
+		Name string `json:"name"`
+	}
+	// maps branch name to the IDs of BranchProtectionRule and Environment nodes it falls under
+	BranchRules        map[string][]string
+	BranchEnvironments map[string][]string
+	// maps branch name to the files found on that branch
+	BranchFiles map[string][]repoFile
+}
+
+// An environment of the repository, along with the branches its custom deployment branch policies
+// allow.
+type repoEnvironment struct {
+	id                 string
+	protectedBranches  bool
+	customBranchPolicy bool
+	policyBranches     []string
+}
+
+type environmentBranchPolicies struct {
+	BranchPolicies []environmentBranchPolicy `json:"branch_policies"`
+}
+
+type environmentBranchPolicy struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+func (ing *BranchFilesIngestor) Sync() {
+	ing.fetchData()
+	ing.insertBranchFiles()
+	ing.insertBranchFilesRules()
+	ing.insertBranchFilesEnvironments()
+}
+
+func (ing *BranchFilesIngestor) fetchData() {
+	ing.data.BranchRules = map[string][]string{}
+	ing.data.BranchEnvironments = map[string][]string{}
+	ing.data.BranchFiles = map[string][]repoFile{}
+
+	ing.fetchProtectedBranches()
+	ing.fetchEnvironmentBranches()
+
+	for branch := range ing.branches() {
+		// files on the default branch are ingested by the repos ingestor
+		if branch == ing.defaultBranch {
+			continue
+		}
+		ing.fetchBranchFiles(branch)
+	}
+}
+
+// Fetches branch protection rules along with the branches they protect.
+func (ing *BranchFilesIngestor) fetchProtectedBranches() {
+	query := `
+	query($login: String!, $repoName: String!, $cursor: String) {
+		repository(owner: $login, name: $repoName) {
+			branchProtectionRules(first: 10, after: $cursor) {
+				pageInfo {
+					endCursor
+					hasNextPage
+				}
+				nodes {
+					pattern
+					matchingRefs(first: 100) {
+						totalCount
+						nodes {
+							name
+						}
+					}
+				}
+			}
+		}
+	}
+	`
+
+	data := ing.gqlclient.fetch(
+		query,
+		"repository.branchProtectionRules",
+		map[string]string{"repoName": ing.repoName},
+	)
+	json.Unmarshal(data, &ing.data.BranchProtectionRules)
+
+	for _, ruleNode := range ing.data.BranchProtectionRules.Nodes {
+		// nb: this is the same ID as the one given by the repos ingestor
+		id := fmt.Sprintf("%x", md5.Sum([]byte(ruleNode.Pattern+ing.repoURL)))
+		if ruleNode.MatchingRefs.TotalCount > len(ruleNode.MatchingRefs.Nodes) {
+			log.Warnf(
+				"Branch protection rule %s of %s matches %d branches, only the first %d are ingested",
+				ruleNode.Pattern,
+				ing.repoName,
+				ruleNode.MatchingRefs.TotalCount,
+				len(ruleNode.MatchingRefs.Nodes),
+			)
+		}
+		for _, ref := range ruleNode.MatchingRefs.Nodes {
+			ing.data.BranchRules[ref.Name] = append(ing.data.BranchRules[ref.Name], id)
+		}
+	}
+}
+
+// Resolves the branches each of the repository's environments can be deployed from.
+func (ing *BranchFilesIngestor) fetchEnvironmentBranches() {
+	records := ing.db.Run(`
+	MATCH (:Repository{id: $repoURL})-[:HAS_ENVIRONMENT]->(e:Environment{session: $session})
+	RETURN e.id AS id, e.name AS name, e.protectedBranches AS protectedBranches,
+	e.customBranchPolicy AS customBranchPolicy
+	`, map[string]interface{}{"repoURL": ing.repoURL, "session": ing.session})
+
+	environments := []repoEnvironment{}
+	for records.Next() {
+		id, _ := records.Record().Get("id")
+		name, _ := records.Record().Get("name")
+		protectedBranches, _ := records.Record().Get("protectedBranches")
+		customBranchPolicy, _ := records.Record().Get("customBranchPolicy")
+
+		environment := repoEnvironment{
+			id:                 id.(string),
+			protectedBranches:  protectedBranches == true,
+			customBranchPolicy: customBranchPolicy == true,
+		}
+		if environment.customBranchPolicy && !environment.protectedBranches {
+			environment.policyBranches = ing.fetchPolicyBranches(name.(string))
+		}
+		environments = append(environments, environment)
+	}
+
+	ing.addEnvironmentsBranches(environments)
+}
+
+// Adds the branches each environment can be deployed from, given the protected branches.
+func (ing *BranchFilesIngestor) addEnvironmentsBranches(environments []repoEnvironment) {
+	// environments without a deployment branch policy can be deployed from any branch, we link them
+	// once we know all the branches we scan for other reasons
+	anyBranchEnvironmentIDs := []string{}
+
+	for _, environment := range environments {
+		switch {
+		case environment.protectedBranches:
+			for branch := range ing.data.BranchRules {
+				ing.addBranchEnvironment(branch, environment.id)
+			}
+		case environment.customBranchPolicy:
+			for _, branch := range environment.policyBranches {
+				ing.addBranchEnvironment(branch, environment.id)
+			}
+		default:
+			anyBranchEnvironmentIDs = append(anyBranchEnvironmentIDs, environment.id)
+		}
+	}
+
+	branches := ing.branches()
+	branches[ing.defaultBranch] = true
+	for branch := range branches {
+		for _, id := range anyBranchEnvironmentIDs {
+			ing.addBranchEnvironment(branch, id)
+		}
+	}
+}
+
+// Returns the repository branches matching an environment's custom deployment branch policies.
+func (ing *BranchFilesIngestor) fetchPolicyBranches(envName string) []string {
+	data := ing.restclient.fetch(fmt.Sprintf(
+		"repos/%s/%s/environments/%s/deployment-branch-policies",
+		ing.restclient.organization,
+		ing.repoName,
+		url.PathEscape(envName),
+	))
+	policies := environmentBranchPolicies{}
+	json.Unmarshal(data, &policies)
+
+	if ing.data.Branches == nil {
+		data := ing.restclient.fetch(
+			fmt.Sprintf("repos/%s/%s/branches", ing.restclient.organization, ing.repoName),
+		)
+		json.Unmarshal(data, &ing.data.Branches)
+	}
+
+	branches := []string{}
+	for _, branch := range ing.data.Branches {
+		branches = append(branches, branch.Name)
+	}
+	return matchPolicyBranches(policies, branches)
+}
+
+// Returns the branches matching any of an environment's deployment branch policies, which use
+// fnmatch patterns.
+func matchPolicyBranches(policies environmentBranchPolicies, branches []string) []string {
+	matches := []string{}
+	for _, branch := range branches {
+		for _, policy := range policies.BranchPolicies {
+			// policies may also target tags, which we don't scan
+			if policy.Type == "tag" {
+				continue
+			}
+			if match, _ := path.Match(policy.Name, branch); match {
+				matches = append(matches, branch)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+func (ing *BranchFilesIngestor) addBranchEnvironment(branch, environmentID string) {
+	if !sliceContains(ing.data.BranchEnvironments[branch], environmentID) {
+		ing.data.BranchEnvironments[branch] = append(
+			ing.data.BranchEnvironments[branch],
+			environmentID,
+		)
+	}
+}
+
+// Returns the set of branches falling under branch protection rules or environments.
+func (ing *BranchFilesIngestor) branches() map[string]bool {
+	branches := map[string]bool{}
+	for branch := range ing.data.BranchRules {
+		branches[branch] = true
+	}
+	for branch := range ing.data.BranchEnvironments {
+		branches[branch] = true
+	}
+	return branches
+}
+
+func (ing *BranchFilesIngestor) fetchBranchFiles(branch string) {
+	query := fmt.Sprintf(`
+	query($login: String!, $repoName: String!) {
+		repository(owner: $login, name: $repoName) {
+			%s
+		}
+	}
+	`, filesQueryFields("refs/heads/"+branch, ing.filePatterns))
+
+	data := ing.gqlclient.query(
+		query,
+		"repository",
+		map[string]string{"repoName": ing.repoName},
+	)
+	fields := map[string]json.RawMessage{}
+	json.Unmarshal(data, &fields)
+
+	ing.data.BranchFiles[branch] = parseFiles(fields, ing.filePatterns)
+}
+
+func (ing *BranchFilesIngestor) insertBranchFiles() {
+	branchFiles := []map[string]interface{}{}
+
+	for branch, files := range ing.data.BranchFiles {
+		for _, file := range files {
+			id := fmt.Sprintf("%x", md5.Sum([]byte(branch+":"+file.path+ing.repoURL)))
+			branchFiles = append(branchFiles, map[string]interface{}{
+				"id":       id,
+				"path":     file.path,
+				"text":     file.text,
+				"textHash": fmt.Sprintf("%x", md5.Sum([]byte(file.text))),
+				"ref":      branch,
+			})
+		}
+	}
+
+	ing.db.Run(`
+	UNWIND $branchFiles as branchFile
+
+	MERGE (f:File{id: branchFile.id})
+
+	SET f.path = branchFile.path,
+	f.text = branchFile.text,
+	f.textHash = branchFile.textHash,
+	f.ref = branchFile.ref,
+	f.session = $session
+
+	WITH f
+
+	MATCH (r:Repository{id: $repoURL})
+	MERGE (r)-[rel:HAS_CI_CONFIGURATION_FILE]->(f)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"branchFiles": branchFiles,
+		"repoURL":     ing.repoURL,
+		"session":     ing.session,
+	})
+}
+
+// Links files, including those on the default branch, to the branch protection rules of their
+// branch.
+func (ing *BranchFilesIngestor) insertBranchFilesRules() {
+	branchRules := []map[string]string{}
+
+	for branch, ruleIDs := range ing.data.BranchRules {
+		for _, ruleID := range ruleIDs {
+			branchRules = append(branchRules, map[string]string{
+				"ref":    branch,
+				"ruleID": ruleID,
+			})
+		}
+	}
+
+	ing.db.Run(`
+	UNWIND $branchRules as branchRule
+
+	MATCH (:Repository{id: $repoURL})-[:HAS_CI_CONFIGURATION_FILE]->(f:File{ref: branchRule.ref})
+	WHERE f.session = $session
+	MATCH (b:BranchProtectionRule{id: branchRule.ruleID})
+	MERGE (f)-[rel:PROTECTED_BY]->(b)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"branchRules": branchRules,
+		"repoURL":     ing.repoURL,
+		"session":     ing.session,
+	})
+}
+
+// Links files, including those on the default branch, to the environments their branch can deploy
+// to.
+func (ing *BranchFilesIngestor) insertBranchFilesEnvironments() {
+	branchEnvironments := []map[string]string{}
+
+	for branch, environmentIDs := range ing.data.BranchEnvironments {
+		for _, environmentID := range environmentIDs {
+			branchEnvironments = append(branchEnvironments, map[string]string{
+				"ref":           branch,
+				"environmentID": environmentID,
+			})
+		}
+	}
+
+	ing.db.Run(`
+	UNWIND $branchEnvironments as branchEnvironment
+
+	MATCH (:Repository{id: $repoURL})-[:HAS_CI_CONFIGURATION_FILE]->(f:File{ref: branchEnvironment.ref})
+	WHERE f.session = $session
+	MATCH (e:Environment{id: branchEnvironment.environmentID})
+	MERGE (f)-[rel:CAN_DEPLOY_TO]->(e)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"branchEnvironments": branchEnvironments,
+		"repoURL":            ing.repoURL,
+		"session":            ing.session,
+	})
+}
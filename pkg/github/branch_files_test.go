@@ -0,0 +1,159 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMatchPolicyBranches(t *testing.T) {
+	branches := []string{"main", "release/1.0", "release/1.0/hotfix", "feature/x", "v1"}
+
+	testCases := []struct {
+		name     string
+		policies environmentBranchPolicies
+		want     []string
+	}{
+		{
+			name:     "exact name",
+			policies: environmentBranchPolicies{BranchPolicies: []environmentBranchPolicy{{Name: "main", Type: "branch"}}},
+			want:     []string{"main"},
+		},
+		{
+			name:     "wildcards don't match slashes",
+			policies: environmentBranchPolicies{BranchPolicies: []environmentBranchPolicy{{Name: "release/*", Type: "branch"}, {Name: "main"}}},
+			want:     []string{"main", "release/1.0"},
+		},
+		{
+			name:     "tag policies",
+			policies: environmentBranchPolicies{BranchPolicies: []environmentBranchPolicy{{Name: "v*", Type: "tag"}}},
+			want:     []string{},
+		},
+		{
+			name:     "no policies",
+			policies: environmentBranchPolicies{},
+			want:     []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := matchPolicyBranches(tc.policies, branches); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("matchPolicyBranches() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAddEnvironmentsBranches(t *testing.T) {
+	testCases := []struct {
+		name         string
+		branchRules  map[string][]string
+		environments []repoEnvironment
+		want         map[string][]string
+	}{
+		{
+			name:        "protected branches",
+			branchRules: map[string][]string{"main": {"rule"}, "release": {"rule"}},
+			environments: []repoEnvironment{
+				{id: "production", protectedBranches: true, customBranchPolicy: false},
+			},
+			want: map[string][]string{"main": {"production"}, "release": {"production"}},
+		},
+		{
+			name:        "custom branch policies",
+			branchRules: map[string][]string{"main": {"rule"}},
+			environments: []repoEnvironment{
+				{id: "staging", customBranchPolicy: true, policyBranches: []string{"develop"}},
+			},
+			want: map[string][]string{"develop": {"staging"}},
+		},
+		{
+			name:        "any branch",
+			branchRules: map[string][]string{"release": {"rule"}},
+			environments: []repoEnvironment{
+				{id: "staging", customBranchPolicy: true, policyBranches: []string{"develop"}},
+				{id: "preview"},
+			},
+			want: map[string][]string{
+				"develop": {"staging", "preview"},
+				"main":    {"preview"},
+				"release": {"preview"},
+			},
+		},
+		{
+			name:         "any branch without other branches",
+			branchRules:  map[string][]string{},
+			environments: []repoEnvironment{{id: "preview"}},
+			want:         map[string][]string{"main": {"preview"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ing := BranchFilesIngestor{
+				data: &BranchFilesData{
+					BranchRules:        tc.branchRules,
+					BranchEnvironments: map[string][]string{},
+				},
+				defaultBranch: "main",
+			}
+			ing.addEnvironmentsBranches(tc.environments)
+
+			for _, environmentIDs := range ing.data.BranchEnvironments {
+				sort.Strings(environmentIDs)
+			}
+			for _, environmentIDs := range tc.want {
+				sort.Strings(environmentIDs)
+			}
+			if !reflect.DeepEqual(ing.data.BranchEnvironments, tc.want) {
+				t.Errorf("BranchEnvironments = %v, want %v", ing.data.BranchEnvironments, tc.want)
+			}
+		})
+	}
+}
+
+func TestFetchPolicyBranches(t *testing.T) {
+	responses := map[string]string{
+		"/repos/fakenews/aws-infra/environments/prod%2Feu%20west/deployment-branch-policies": `{
+			"total_count": 2,
+			"branch_policies": [
+				{"name": "release/*", "type": "branch"},
+				{"name": "v*", "type": "tag"}
+			]
+		}`,
+		"/repos/fakenews/aws-infra/branches": `[
+			{"name": "main"},
+			{"name": "release/1.0"},
+			{"name": "v1"}
+		]`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.EscapedPath()]
+		if !ok || r.Header.Get("Authorization") != "token fake" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+			return
+		}
+		fmt.Fprint(w, response)
+	}))
+	defer server.Close()
+
+	ing := BranchFilesIngestor{
+		restclient: &RESTClient{
+			client:        &http.Client{},
+			token:         "fake",
+			organization:  "fakenews",
+			githubRESTURL: server.URL,
+		},
+		data:     &BranchFilesData{},
+		repoName: "aws-infra",
+	}
+
+	if got, want := ing.fetchPolicyBranches("prod/eu west"), []string{"release/1.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fetchPolicyBranches() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,158 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFilePatterns are the CI/CD configuration files we look for in every repository. Patterns
+// are relative to the repository root and support the wildcards of path.Match, which don't match
+// across path separators (e.g. ".github/actions/*/action.yml").
+var DefaultFilePatterns = []string{
+	".circleci/config.yml",
+	".travis.yml",
+	"Jenkinsfile",
+	"buildspec.yml",
+	"cloudbuild.yaml",
+	"build.sbt",
+	".github/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+	".github/workflows/*",
+	".github/actions/*/action.yml",
+	".github/actions/*/action.yaml",
+	"action.yml",
+	"action.yaml",
+}
+
+// FilesConfig is the format of the configuration file passed to the GitHub command to extend the
+// list of files we ingest.
+type FilesConfig struct {
+	Files []string `yaml:"files"`
+}
+
+// A file fetched from a repository.
+type repoFile struct {
+	path string
+	text string
+}
+
+// A Git object as returned by the GraphQL fields built with filesQueryFields. Blobs have text and
+// trees have entries.
+type fileObject struct {
+	Text    string `json:"text"`
+	Entries []struct {
+		Name   string     `json:"name"`
+		Object fileObject `json:"object"`
+	} `json:"entries"`
+}
+
+// Returns DefaultFilePatterns extended with the patterns in the YAML (or JSON) configuration file at
+// configPath.
+func LoadFilePatterns(configPath string) ([]string, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := FilesConfig{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", configPath, err)
+	}
+
+	patterns := append([]string{}, DefaultFilePatterns...)
+	for _, pattern := range config.Files {
+		pattern = strings.Trim(pattern, "/")
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return nil, fmt.Errorf("invalid file pattern '%s' in %s", pattern, configPath)
+		}
+		if !sliceContains(patterns, pattern) {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns, nil
+}
+
+// Splits a pattern into the longest directory prefix without wildcards and the number of path
+// segments left to match under it.
+func splitFilePattern(pattern string) (string, int) {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if strings.ContainsAny(segment, `*?[\`) {
+			return strings.Join(segments[:i], "/"), len(segments) - i
+		}
+	}
+	return pattern, 0
+}
+
+// Returns the GraphQL selection for an object that is depth trees above the blobs we want.
+func fileObjectSelection(depth int) string {
+	if depth == 0 {
+		return "... on Blob { text }"
+	}
+	return fmt.Sprintf("... on Tree { entries { name object { %s } } }", fileObjectSelection(depth-1))
+}
+
+// Returns GraphQL repository fields fetching the objects needed to resolve patterns at ref. Fields
+// are aliased file0, file1, ... in the same order as patterns.
+func filesQueryFields(ref string, patterns []string) string {
+	fields := []string{}
+	for i, pattern := range patterns {
+		prefix, depth := splitFilePattern(pattern)
+		fields = append(fields, fmt.Sprintf(
+			`file%d: object(expression: %q) { %s }`,
+			i,
+			ref+":"+prefix,
+			fileObjectSelection(depth),
+		))
+	}
+	return strings.Join(fields, "\n")
+}
+
+// Returns the non-empty files matching patterns from repository fields fetched with the query
+// built by filesQueryFields.
+func parseFiles(fields map[string]json.RawMessage, patterns []string) []repoFile {
+	files := []repoFile{}
+	seen := map[string]bool{}
+
+	for i, pattern := range patterns {
+		raw, ok := fields[fmt.Sprintf("file%d", i)]
+		if !ok {
+			continue
+		}
+		object := fileObject{}
+		if err := json.Unmarshal(raw, &object); err != nil {
+			continue
+		}
+
+		prefix, _ := splitFilePattern(pattern)
+		for _, file := range walkFileObject(prefix, object) {
+			if match, _ := path.Match(pattern, file.path); !match || seen[file.path] {
+				continue
+			}
+			seen[file.path] = true
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
+// Returns all blobs with text under object, which is found at objectPath.
+func walkFileObject(objectPath string, object fileObject) []repoFile {
+	if object.Text != "" {
+		return []repoFile{{path: objectPath, text: object.Text}}
+	}
+
+	files := []repoFile{}
+	for _, entry := range object.Entries {
+		files = append(files, walkFileObject(path.Join(objectPath, entry.Name), entry.Object)...)
+	}
+	return files
+}
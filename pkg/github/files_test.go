@@ -0,0 +1,141 @@
+package github
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitFilePattern(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		prefix  string
+		depth   int
+	}{
+		{".circleci/config.yml", ".circleci/config.yml", 0},
+		{".github/workflows/*", ".github/workflows", 1},
+		{".github/actions/*/action.yml", ".github/actions", 2},
+		{"*.yml", "", 1},
+		{"ci/build-?.yml", "ci", 1},
+		{"ci/[ab]/x", "ci", 2},
+	}
+
+	for _, tc := range testCases {
+		prefix, depth := splitFilePattern(tc.pattern)
+		if prefix != tc.prefix || depth != tc.depth {
+			t.Errorf("splitFilePattern(%q) = %q, %d, want %q, %d", tc.pattern, prefix, depth, tc.prefix, tc.depth)
+		}
+	}
+}
+
+func TestFilesQueryFields(t *testing.T) {
+	fields := filesQueryFields("main", []string{"Jenkinsfile", ".github/workflows/*"})
+
+	want := []string{
+		`file0: object(expression: "main:Jenkinsfile") { ... on Blob { text } }`,
+		`file1: object(expression: "main:.github/workflows") { ... on Tree { entries { name object { ... on Blob { text } } } } }`,
+	}
+	if fields != strings.Join(want, "\n") {
+		t.Errorf("filesQueryFields() = %s", fields)
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	patterns := []string{"Jenkinsfile", ".github/workflows/*.yml", ".github/workflows/*"}
+	fields := map[string]json.RawMessage{}
+	json.Unmarshal([]byte(`{
+		"file0": {"text": "pipeline {}"},
+		"file1": {"entries": [
+			{"name": "ci.yml", "object": {"text": "on: push"}},
+			{"name": "README.md", "object": {"text": "docs"}},
+			{"name": "empty.yml", "object": {}}
+		]},
+		"file2": {"entries": [
+			{"name": "ci.yml", "object": {"text": "on: push"}},
+			{"name": "release.yaml", "object": {"text": "on: release"}}
+		]}
+	}`), &fields)
+
+	files := parseFiles(fields, patterns)
+
+	want := []repoFile{
+		{path: "Jenkinsfile", text: "pipeline {}"},
+		{path: ".github/workflows/ci.yml", text: "on: push"},
+		{path: ".github/workflows/release.yaml", text: "on: release"},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("parseFiles() = %+v, want %+v", files, want)
+	}
+}
+
+func TestParseFilesMissingObjects(t *testing.T) {
+	fields := map[string]json.RawMessage{"file0": json.RawMessage("null")}
+
+	if files := parseFiles(fields, []string{"Jenkinsfile", ".travis.yml"}); len(files) != 0 {
+		t.Errorf("parseFiles() = %+v, want no files", files)
+	}
+}
+
+func TestLoadFilePatterns(t *testing.T) {
+	testCases := []struct {
+		name    string
+		config  string
+		extra   []string
+		wantErr bool
+	}{
+		{
+			name:   "yaml",
+			config: "files:\n  - /.gitlab-ci.yml\n  - .github/actions/*/action.yml\n  - Jenkinsfile\n",
+			extra:  []string{".gitlab-ci.yml"},
+		},
+		{
+			name:   "json",
+			config: `{"files": ["azure-pipelines.yml"]}`,
+			extra:  []string{"azure-pipelines.yml"},
+		},
+		{
+			name:    "invalid pattern",
+			config:  "files:\n  - '[a'\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty pattern",
+			config:  "files:\n  - /\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid yaml",
+			config:  "files: [",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "files.yml")
+			os.WriteFile(configPath, []byte(tc.config), 0600)
+
+			patterns, err := LoadFilePatterns(configPath)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("LoadFilePatterns() = %v, want an error", patterns)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := append(append([]string{}, DefaultFilePatterns...), tc.extra...)
+			if !reflect.DeepEqual(patterns, want) {
+				t.Errorf("LoadFilePatterns() = %v, want %v", patterns, want)
+			}
+		})
+	}
+
+	if _, err := LoadFilePatterns(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Error("LoadFilePatterns() of a missing file should fail")
+	}
+}
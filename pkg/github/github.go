@@ -8,13 +8,18 @@ import (
 )
 
 type GitHub struct {
-	gqlclient  *GraphQLClient
-	restclient *RESTClient
-	db         *database.Database
-	session    string
+	gqlclient    *GraphQLClient
+	restclient   *RESTClient
+	db           *database.Database
+	filePatterns []string
+	session      string
 }
 
-func GetGitHub(db *database.Database, githubRESTURL, githubGraphQLURL, token, organization, session string) *GitHub {
+func GetGitHub(
+	db *database.Database,
+	githubRESTURL, githubGraphQLURL, token, organization, session string,
+	filePatterns []string,
+) *GitHub {
 	return &GitHub{
 		gqlclient: &GraphQLClient{
 			client:           &http.Client{},
@@ -28,8 +33,9 @@ func GetGitHub(db *database.Database, githubRESTURL, githubGraphQLURL, token, or
 			token:         token,
 			organization:  organization,
 		},
-		db:      db,
-		session: session,
+		db:           db,
+		filePatterns: filePatterns,
+		session:      session,
 	}
 }
 
@@ -56,6 +62,7 @@ func (g *GitHub) runOrgIngestors(targetIngestors []string) {
 		"users",
 		"repos",
 		"organizationsecrets",
+		"organizationoidc",
 	}
 	orgIngestors := map[string]Ingestor{
 		"organizations": &OrganizationsIngestor{
@@ -77,10 +84,12 @@ func (g *GitHub) runOrgIngestors(targetIngestors []string) {
 			session:   g.session,
 		},
 		"repos": &ReposIngestor{
-			gqlclient: g.gqlclient,
-			db:        g.db,
-			data:      &ReposData{},
-			session:   g.session,
+			gqlclient:    g.gqlclient,
+			db:           g.db,
+			data:         &ReposData{},
+			filesData:    &ReposFilesData{},
+			filePatterns: g.filePatterns,
+			session:      g.session,
 		},
 		"organizationsecrets": &OrganizationSecretsIngestor{
 			restclient: g.restclient,
@@ -88,6 +97,12 @@ func (g *GitHub) runOrgIngestors(targetIngestors []string) {
 			data:       &OrganizationSecretsData{},
 			session:    g.session,
 		},
+		"organizationoidc": &OrganizationOIDCIngestor{
+			restclient: g.restclient,
+			db:         g.db,
+			data:       &OrganizationOIDCData{},
+			session:    g.session,
+		},
 	}
 
 	for _, name := range orgIngestorOrderedKeys {
@@ -140,14 +155,31 @@ func (g *GitHub) runTeamIngestors(targetIngestors []string) {
 // Runs repository ingestors if they're in targetIngestors. Repository ingestors operate at a
 // specific repo level.
 func (g *GitHub) runRepoIngestors(targetIngestors []string) {
+	// NB: order matters for these! Branch files are linked to environments.
+	repoIngestorOrderedKeys := []string{
+		"repowebhooks",
+		"environments",
+		"reposecrets",
+		"repooidc",
+		"branchfiles",
+	}
+
 	repoRecords := g.db.Run(`
-		MATCH (r:Repository) RETURN r.name as repoName, r.databaseId as repoId
+		MATCH (r:Repository)
+		RETURN r.name as repoName, r.databaseId as repoId, r.url as repoURL,
+		r.defaultBranch as defaultBranch
 		`,
 		map[string]interface{}{"session": g.session},
 	)
 	for repoRecords.Next() {
 		repoName, _ := repoRecords.Record().Get("repoName")
 		repoId, _ := repoRecords.Record().Get("repoId")
+		repoURL, _ := repoRecords.Record().Get("repoURL")
+		defaultBranch, _ := repoRecords.Record().Get("defaultBranch")
+		// repositories without commits don't have a default branch
+		if defaultBranch == nil {
+			defaultBranch = ""
+		}
 
 		repoIngestors := map[string]Ingestor{
 			"repowebhooks": &RepoWebhooksIngestor{
@@ -169,14 +201,33 @@ func (g *GitHub) runRepoIngestors(targetIngestors []string) {
 				repoId:     repoId.(int64),
 				session:    g.session,
 			},
+			"repooidc": &RepoOIDCIngestor{
+				restclient: g.restclient,
+				db:         g.db,
+				data:       &RepoOIDCData{},
+				repoName:   repoName.(string),
+				repoURL:    repoURL.(string),
+				session:    g.session,
+			},
+			"branchfiles": &BranchFilesIngestor{
+				gqlclient:     g.gqlclient,
+				restclient:    g.restclient,
+				db:            g.db,
+				data:          &BranchFilesData{},
+				filePatterns:  g.filePatterns,
+				repoName:      repoName.(string),
+				repoURL:       repoURL.(string),
+				defaultBranch: defaultBranch.(string),
+				session:       g.session,
+			},
 		}
 
-		for name, ingestor := range repoIngestors {
+		for _, name := range repoIngestorOrderedKeys {
 			if !sliceContains(targetIngestors, name) {
 				continue
 			}
 			log.Infof("Running repo ingestor %s on repo %s", name, repoName)
-			ingestor.Sync()
+			repoIngestors[name].Sync()
 		}
 
 		g.runRepoEnvironmentIngestors(targetIngestors, repoName.(string), repoId.(int64))
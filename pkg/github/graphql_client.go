@@ -134,6 +134,28 @@ func (c *GraphQLClient) fetch(query, resourcePath string, variables map[string]s
 	return data.Bytes()
 }
 
+// Retrieves data in resourcePath for a GraphQL query that isn't paginated.
+func (c *GraphQLClient) query(query, resourcePath string, variables map[string]string) []byte {
+	variables["login"] = c.organization
+
+	resp := c.call(query, variables)
+
+	parsedResp, err := gabs.ParseJSON(resp)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	gqlerrors := parsedResp.Path("errors")
+	if gqlerrors != nil {
+		c.checkFatalErrors(gqlerrors)
+		errorTracker := map[string]GraphQLError{}
+		c.trackFetchErrors(gqlerrors, errorTracker)
+		c.logFetchErrors(resourcePath, errorTracker)
+	}
+
+	return parsedResp.Path(fmt.Sprintf("data.%s", resourcePath)).Bytes()
+}
+
 // Takes errors container received from a GraphQL JSON response and updates the errorTracker.
 func (c *GraphQLClient) trackFetchErrors(
 	errors *gabs.Container,
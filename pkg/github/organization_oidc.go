@@ -0,0 +1,42 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ovotech/gitoops/pkg/database"
+)
+
+// Ingests the organization's customization of the subject claim in GitHub Actions OIDC tokens.
+type OrganizationOIDCIngestor struct {
+	restclient *RESTClient
+	db         *database.Database
+	data       *OrganizationOIDCData
+	session    string
+}
+
+type OrganizationOIDCData struct {
+	IncludeClaimKeys []string `json:"include_claim_keys"`
+}
+
+func (ing *OrganizationOIDCIngestor) Sync() {
+	ing.fetchData()
+	ing.insertOrganizationOIDC()
+}
+
+func (ing *OrganizationOIDCIngestor) fetchData() {
+	query := fmt.Sprintf("orgs/%s/actions/oidc/customization/sub", ing.restclient.organization)
+
+	data := ing.restclient.fetch(query)
+	json.Unmarshal(data, &ing.data)
+}
+
+func (ing *OrganizationOIDCIngestor) insertOrganizationOIDC() {
+	ing.db.Run(`
+	MATCH (o:Organization{login: $organization})
+	SET o.oidcSubClaimKeys = $claimKeys
+	`, map[string]interface{}{
+		"organization": ing.restclient.organization,
+		"claimKeys":    ing.data.IncludeClaimKeys,
+	})
+}
@@ -0,0 +1,53 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ovotech/gitoops/pkg/database"
+)
+
+// Ingests a repository's customization of the subject claim in GitHub Actions OIDC tokens.
+type RepoOIDCIngestor struct {
+	restclient *RESTClient
+	db         *database.Database
+	data       *RepoOIDCData
+	repoName   string
+	repoURL    string
+	session    string
+}
+
+type RepoOIDCData struct {
+	UseDefault       bool     `json:"use_default"`
+	IncludeClaimKeys []string `json:"include_claim_keys"`
+}
+
+func (ing *RepoOIDCIngestor) Sync() {
+	ing.fetchData()
+	ing.insertRepoOIDC()
+}
+
+func (ing *RepoOIDCIngestor) fetchData() {
+	query := fmt.Sprintf(
+		"repos/%s/%s/actions/oidc/customization/sub",
+		ing.restclient.organization,
+		ing.repoName,
+	)
+
+	// repositories that were never customized use the default subject claim
+	ing.data.UseDefault = true
+	data := ing.restclient.fetch(query)
+	json.Unmarshal(data, &ing.data)
+}
+
+func (ing *RepoOIDCIngestor) insertRepoOIDC() {
+	ing.db.Run(`
+	MATCH (r:Repository{id: $repoURL})
+	SET r.oidcUseDefault = $useDefault,
+	r.oidcSubClaimKeys = $claimKeys
+	`, map[string]interface{}{
+		"repoURL":    ing.repoURL,
+		"useDefault": ing.data.UseDefault,
+		"claimKeys":  ing.data.IncludeClaimKeys,
+	})
+}
@@ -7,13 +7,16 @@ import (
 	"net/url"
 
 	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
 )
 
 type ReposIngestor struct {
-	gqlclient *GraphQLClient
-	db        *database.Database
-	data      *ReposData
-	session   string
+	gqlclient    *GraphQLClient
+	db           *database.Database
+	data         *ReposData
+	filesData    *ReposFilesData
+	filePatterns []string
+	session      string
 }
 
 type ReposData struct {
@@ -32,39 +35,21 @@ type ReposData struct {
 				Login string `json:"login"`
 			} `json:"nodes"`
 		} `json:"collaborators"`
-		CircleCI struct {
-			Text string `json:"text"`
-		} `json:"circleci"`
-		Travis struct {
-			Text string `json:"text"`
-		} `json:"travis"`
-		Jenkins struct {
-			Text string `json:"text"`
-		} `json:"jenkins"`
-		CodeBuild struct {
-			Text string `json:"text"`
-		} `json:"codebuild"`
-		CloudBuild struct {
-			Text string `json:"text"`
-		} `json:"cloudbuild"`
-		BuildSBT struct {
-			Text string `json:"text"`
-		} `json:"buildsbt"`
-		Codeowners struct {
-			Text string `json:"text"`
-		} `json:"codeowners"`
-		Actions struct {
-			Entries []struct {
-				Name   string `json:"name"`
-				Object struct {
-					Text string `json:"text"`
-				} `json:"object"`
-			} `json:"entries"`
-		} `json:"actions"`
+		DeployKeys struct {
+			PageInfo struct {
+				HasNextPage bool `json:"hasNextPage"`
+			} `json:"pageInfo"`
+			Nodes []struct {
+				Key      string `json:"key"`
+				Title    string `json:"title"`
+				ReadOnly bool   `json:"readOnly"`
+			} `json:"nodes"`
+		} `json:"deployKeys"`
 		BranchProtectionRules struct {
 			Nodes []struct {
 				Pattern                  string `json:"pattern"`
 				RequiresApprovingReviews bool   `json:"requiresApprovingReviews"`
+				RequiresCodeOwnerReviews bool   `json:"requiresCodeOwnerReviews"`
 			} `json:"nodes"`
 		} `json:"branchProtectionRules"`
 		PullRequests struct {
@@ -85,6 +70,7 @@ type ReposData struct {
 			} `json:"nodes"`
 		} `json:"pullRequests"`
 		DefaultBranchRef struct {
+			Name   string `json:"name"`
 			Target struct {
 				History struct {
 					Edges []struct {
@@ -104,6 +90,12 @@ type ReposData struct {
 	} `json:"nodes"`
 }
 
+// Holds the CI/CD configuration files fields of each repository node, which are generated from the
+// ingestor's file patterns and so can't be declared in ReposData.
+type ReposFilesData struct {
+	Nodes []map[string]json.RawMessage `json:"nodes"`
+}
+
 func (ing *ReposIngestor) Sync() {
 	ing.fetchData()
 	ing.insertRepos()
@@ -112,10 +104,11 @@ func (ing *ReposIngestor) Sync() {
 	ing.insertReposPullRequestsStatusChecks()
 	ing.insertReposDefaultBranchStatusChecks()
 	ing.insertReposBranchProtectionRules()
+	ing.insertReposDeployKeys()
 }
 
 func (ing *ReposIngestor) fetchData() {
-	query := `
+	query := fmt.Sprintf(`
 	query($login: String!, $cursor: String) {
 		organization(login: $login) {
 			repositories(first: 10, after: $cursor) {
@@ -138,57 +131,22 @@ func (ing *ReposIngestor) fetchData() {
 							login
 						}
 					}
-					circleci: object(expression: "HEAD:.circleci/config.yml") {
-						... on Blob {
-							text
-						}
-					}
-					travis: object(expression: "HEAD:.travis.yml") {
-						... on Blob {
-							text
-						}
-					}
-					jenkins: object(expression: "HEAD:Jenkinsfile") {
-						... on Blob {
-							text
+					%s
+					deployKeys(first: 100) {
+						pageInfo {
+							hasNextPage
 						}
-					}
-					codebuild: object(expression: "HEAD:buildspec.yml") {
-						... on Blob {
-							text
-						}
-					}
-					cloudbuild: object(expression: "HEAD:cloudbuild.yaml") {
-						... on Blob {
-							text
-						}
-					}
-					buildsbt: object(expression: "HEAD:build.sbt") {
-						... on Blob {
-							text
-						}
-					}
-					codeowners:object(expression: "HEAD:.github/CODEOWNERS") {
-						... on Blob {
-							text
-						}
-					}
-					actions:object(expression: "HEAD:.github/workflows") {
-						... on Tree {
-							entries {
-								name
-								object {
-									... on Blob {
-										text
-									}
-								}
-							}
+						nodes {
+							key
+							title
+							readOnly
 						}
 					}
 					branchProtectionRules(first: 5) {
 						nodes {
 							pattern
 							requiresApprovingReviews
+							requiresCodeOwnerReviews
 						}
           			}
 					pullRequests(last: 10) {
@@ -209,6 +167,7 @@ func (ing *ReposIngestor) fetchData() {
 						}
 					}
 					defaultBranchRef {
+						name
 						target {
 							... on Commit {
 								history(first: 10) {
@@ -231,7 +190,7 @@ func (ing *ReposIngestor) fetchData() {
 			}
 		}
 	}
-	`
+	`, filesQueryFields("HEAD", ing.filePatterns))
 
 	data := ing.gqlclient.fetch(
 		query,
@@ -240,6 +199,7 @@ func (ing *ReposIngestor) fetchData() {
 	)
 
 	json.Unmarshal(data, &ing.data)
+	json.Unmarshal(data, &ing.filesData)
 }
 
 func (ing *ReposIngestor) insertRepos() {
@@ -247,12 +207,13 @@ func (ing *ReposIngestor) insertRepos() {
 
 	for _, repoNode := range ing.data.Nodes {
 		repos = append(repos, map[string]interface{}{
-			"databaseId":   repoNode.DatabaseId,
-			"url":          repoNode.URL,
-			"name":         repoNode.Name,
-			"isPrivate":    repoNode.IsPrivate,
-			"isArchived":   repoNode.IsArchived,
-			"organization": ing.gqlclient.organization,
+			"databaseId":    repoNode.DatabaseId,
+			"url":           repoNode.URL,
+			"name":          repoNode.Name,
+			"isPrivate":     repoNode.IsPrivate,
+			"isArchived":    repoNode.IsArchived,
+			"defaultBranch": repoNode.DefaultBranchRef.Name,
+			"organization":  ing.gqlclient.organization,
 		})
 	}
 
@@ -266,6 +227,7 @@ func (ing *ReposIngestor) insertRepos() {
 	r.name = repo.name,
 	r.isPrivate = repo.isPrivate,
 	r.isArchived = repo.isArchived,
+	r.defaultBranch = repo.defaultBranch,
 	r.session = $session
 
 	WITH r, repo
@@ -310,81 +272,18 @@ func (ing *ReposIngestor) insertReposCollaborators() {
 func (ing *ReposIngestor) insertReposFiles() {
 	reposFiles := []map[string]interface{}{}
 
-	for _, repoNode := range ing.data.Nodes {
-		if repoNode.CircleCI.Text != "" {
-			id := fmt.Sprintf("%x", md5.Sum([]byte("circleci"+repoNode.URL)))
-			reposFiles = append(reposFiles, map[string]interface{}{
-				"id":     id,
-				"path":   ".circleci/config.yml",
-				"text":   repoNode.CircleCI.Text,
-				"repoID": repoNode.URL,
-			})
-		}
-		if repoNode.Travis.Text != "" {
-			id := fmt.Sprintf("%x", md5.Sum([]byte("travis"+repoNode.URL)))
-			reposFiles = append(reposFiles, map[string]interface{}{
-				"id":     id,
-				"path":   ".travis.yml",
-				"text":   repoNode.Travis.Text,
-				"repoID": repoNode.URL,
-			})
-		}
-		if repoNode.Jenkins.Text != "" {
-			id := fmt.Sprintf("%x", md5.Sum([]byte("jenkins"+repoNode.URL)))
-			reposFiles = append(reposFiles, map[string]interface{}{
-				"id":     id,
-				"path":   "Jenkinsfile",
-				"text":   repoNode.Jenkins.Text,
-				"repoID": repoNode.URL,
-			})
-		}
-		if repoNode.CodeBuild.Text != "" {
-			id := fmt.Sprintf("%x", md5.Sum([]byte("codebuild"+repoNode.URL)))
-			reposFiles = append(reposFiles, map[string]interface{}{
-				"id":     id,
-				"path":   "buildspec.yml",
-				"text":   repoNode.CodeBuild.Text,
-				"repoID": repoNode.URL,
-			})
-		}
-		if repoNode.CloudBuild.Text != "" {
-			id := fmt.Sprintf("%x", md5.Sum([]byte("cloudbuild"+repoNode.URL)))
-			reposFiles = append(reposFiles, map[string]interface{}{
-				"id":     id,
-				"path":   "cloudbuild.yaml",
-				"text":   repoNode.CloudBuild.Text,
-				"repoID": repoNode.URL,
-			})
-		}
-		if repoNode.BuildSBT.Text != "" {
-			id := fmt.Sprintf("%x", md5.Sum([]byte("buildsbt"+repoNode.URL)))
+	for i, repoNode := range ing.data.Nodes {
+		for _, file := range parseFiles(ing.filesData.Nodes[i], ing.filePatterns) {
+			id := fmt.Sprintf("%x", md5.Sum([]byte(file.path+repoNode.URL)))
 			reposFiles = append(reposFiles, map[string]interface{}{
-				"id":     id,
-				"path":   "build.sbt",
-				"text":   repoNode.BuildSBT.Text,
-				"repoID": repoNode.URL,
+				"id":       id,
+				"path":     file.path,
+				"text":     file.text,
+				"textHash": fmt.Sprintf("%x", md5.Sum([]byte(file.text))),
+				"ref":      repoNode.DefaultBranchRef.Name,
+				"repoID":   repoNode.URL,
 			})
 		}
-		if repoNode.Codeowners.Text != "" {
-			id := fmt.Sprintf("%x", md5.Sum([]byte("codeowners"+repoNode.URL)))
-			reposFiles = append(reposFiles, map[string]interface{}{
-				"id":     id,
-				"path":   ".github/CODEOWNERS",
-				"text":   repoNode.Codeowners.Text,
-				"repoID": repoNode.URL,
-			})
-		}
-		if len(repoNode.Actions.Entries) > 0 {
-			for _, entry := range repoNode.Actions.Entries {
-				id := fmt.Sprintf("%x", md5.Sum([]byte("actions"+entry.Name+repoNode.URL)))
-				reposFiles = append(reposFiles, map[string]interface{}{
-					"id":     id,
-					"path":   ".github/workflows/" + entry.Name,
-					"text":   entry.Object.Text,
-					"repoID": repoNode.URL,
-				})
-			}
-		}
 	}
 
 	ing.db.Run(`
@@ -394,6 +293,8 @@ func (ing *ReposIngestor) insertReposFiles() {
 
 	SET f.path = repoFile.path,
 	f.text = repoFile.text,
+	f.textHash = repoFile.textHash,
+	f.ref = repoFile.ref,
 	f.session = $session
 
 	WITH f, repoFile
@@ -497,10 +398,11 @@ func (ing *ReposIngestor) insertReposBranchProtectionRules() {
 			reposBranchProtectionRules = append(
 				reposBranchProtectionRules,
 				map[string]interface{}{
-					"id":              id,
-					"repoID":          repoNode.URL,
-					"pattern":         ruleNode.Pattern,
-					"requiresReviews": ruleNode.RequiresApprovingReviews,
+					"id":                       id,
+					"repoID":                   repoNode.URL,
+					"pattern":                  ruleNode.Pattern,
+					"requiresReviews":          ruleNode.RequiresApprovingReviews,
+					"requiresCodeOwnerReviews": ruleNode.RequiresCodeOwnerReviews,
 				},
 			)
 		}
@@ -513,6 +415,7 @@ func (ing *ReposIngestor) insertReposBranchProtectionRules() {
 
 	SET b.pattern = rule.pattern,
 	b.requiresReviews = rule.requiresReviews,
+	b.requiresCodeOwnerReviews = rule.requiresCodeOwnerReviews,
 	b.session = $session
 
 	WITH b, rule
@@ -521,3 +424,44 @@ func (ing *ReposIngestor) insertReposBranchProtectionRules() {
 	SET rel.session = $session
 	`, map[string]interface{}{"reposBranchProtectionRules": reposBranchProtectionRules, "session": ing.session})
 }
+
+// Deploy keys are SSHKey nodes identified by their fingerprint, so we can match them with the keys
+// CI/CD systems hold.
+func (ing *ReposIngestor) insertReposDeployKeys() {
+	reposDeployKeys := []map[string]interface{}{}
+
+	for _, repoNode := range ing.data.Nodes {
+		if repoNode.DeployKeys.PageInfo.HasNextPage {
+			log.Warnf("Repository %s has more than 100 deploy keys, only the first 100 are ingested", repoNode.Name)
+		}
+		for _, keyNode := range repoNode.DeployKeys.Nodes {
+			fingerprint := sshKeyFingerprint(keyNode.Key)
+			if fingerprint == "" {
+				continue
+			}
+			reposDeployKeys = append(reposDeployKeys, map[string]interface{}{
+				"fingerprint": fingerprint,
+				"title":       keyNode.Title,
+				"readOnly":    keyNode.ReadOnly,
+				"repoID":      repoNode.URL,
+			})
+		}
+	}
+
+	ing.db.Run(`
+	UNWIND $reposDeployKeys as deployKey
+
+	MERGE (k:SSHKey{id: deployKey.fingerprint})
+
+	SET k:DeployKey,
+	k.fingerprint = deployKey.fingerprint,
+	k.session = $session
+
+	WITH k, deployKey
+	MATCH (r:Repository{id: deployKey.repoID})
+	MERGE (k)-[rel:IS_DEPLOY_KEY_FOR]->(r)
+	SET rel.title = deployKey.title,
+	rel.readOnly = deployKey.readOnly,
+	rel.session = $session
+	`, map[string]interface{}{"reposDeployKeys": reposDeployKeys, "session": ing.session})
+}
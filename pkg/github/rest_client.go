@@ -28,8 +28,10 @@ type RESTError struct {
 func (c *RESTClient) call(resourcePath string, page int) (int, []byte) {
 	log.Debugf("Issuing REST query %s page %d", resourcePath, page)
 
+	// resource paths are escaped, e.g. names with slashes are given as url.PathEscape(name)
 	u, _ := url.Parse(c.githubRESTURL)
-	u.Path = path.Join(u.Path, resourcePath)
+	u.RawPath = path.Join(u.EscapedPath(), resourcePath)
+	u.Path, _ = url.PathUnescape(u.RawPath)
 	req, err := http.NewRequest(
 		"GET",
 		u.String(),
@@ -0,0 +1,27 @@
+package github
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Returns the MD5 fingerprint of an OpenSSH public key (e.g. "ssh-ed25519 AAAA... comment"), in
+// the colon-separated hex format CircleCI uses, or an empty string if the key can't be decoded.
+func sshKeyFingerprint(publicKey string) string {
+	fields := strings.Fields(publicKey)
+	if len(fields) < 2 {
+		return ""
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return ""
+	}
+
+	hexBytes := []string{}
+	for _, b := range md5.Sum(blob) {
+		hexBytes = append(hexBytes, fmt.Sprintf("%02x", b))
+	}
+	return strings.Join(hexBytes, ":")
+}
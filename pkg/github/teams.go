@@ -16,9 +16,10 @@ type TeamsIngestor struct {
 type TeamsData struct {
 	Edges []struct {
 		Node struct {
-			Name string `json:"name"`
-			URL  string `json:"url"`
-			Slug string `json:"slug"`
+			Name       string `json:"name"`
+			URL        string `json:"url"`
+			Slug       string `json:"slug"`
+			DatabaseID int64  `json:"databaseId"`
 		} `json:"node"`
 	} `json:"edges"`
 }
@@ -42,6 +43,7 @@ func (ing *TeamsIngestor) fetchData() {
 						name
 						url
 						slug
+						databaseId
 					}
 				}
 				nodes {
@@ -70,13 +72,14 @@ func (ing *TeamsIngestor) fetchData() {
 }
 
 func (ing *TeamsIngestor) insertTeams() {
-	teams := []map[string]string{}
+	teams := []map[string]interface{}{}
 
 	for _, teamData := range ing.data.Edges {
-		teams = append(teams, map[string]string{
-			"url":  teamData.Node.URL,
-			"name": teamData.Node.Name,
-			"slug": teamData.Node.Slug,
+		teams = append(teams, map[string]interface{}{
+			"url":        teamData.Node.URL,
+			"name":       teamData.Node.Name,
+			"slug":       teamData.Node.Slug,
+			"databaseId": teamData.Node.DatabaseID,
 		})
 	}
 
@@ -88,6 +91,7 @@ func (ing *TeamsIngestor) insertTeams() {
 	SET t.name = team.name,
 	t.url = team.url,
 	t.slug = team.slug,
+	t.databaseId = team.databaseId,
 	t.session = $session
 	`, map[string]interface{}{"teams": teams, "session": ing.session})
 }
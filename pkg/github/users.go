@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 
 	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
 )
 
 type UsersIngestor struct {
@@ -18,14 +19,23 @@ type UsersData struct {
 		Role string `json:"role"`
 	} `json:"edges"`
 	Nodes []struct {
-		Login string `json:"login"`
-		URL   string `json:"url"`
+		Login      string `json:"login"`
+		URL        string `json:"url"`
+		PublicKeys struct {
+			PageInfo struct {
+				HasNextPage bool `json:"hasNextPage"`
+			} `json:"pageInfo"`
+			Nodes []struct {
+				Key string `json:"key"`
+			} `json:"nodes"`
+		} `json:"publicKeys"`
 	} `json:"nodes"`
 }
 
 func (ing *UsersIngestor) Sync() {
 	ing.fetchData()
 	ing.insertUsers()
+	ing.insertUsersKeys()
 }
 
 func (ing *UsersIngestor) fetchData() {
@@ -43,6 +53,14 @@ func (ing *UsersIngestor) fetchData() {
 				nodes {
 					login
 					url
+					publicKeys(first: 100) {
+						pageInfo {
+							hasNextPage
+						}
+						nodes {
+							key
+						}
+					}
 				}
 			}
 		}
@@ -86,3 +104,40 @@ func (ing *UsersIngestor) insertUsers() {
 	SET rel.session = $session
 	`, map[string]interface{}{"users": users, "session": ing.session})
 }
+
+// Users' SSH keys are SSHKey nodes identified by their fingerprint, so we can match them with the
+// keys CI/CD systems hold.
+func (ing *UsersIngestor) insertUsersKeys() {
+	usersKeys := []map[string]string{}
+
+	for _, userNode := range ing.data.Nodes {
+		if userNode.PublicKeys.PageInfo.HasNextPage {
+			log.Warnf("User %s has more than 100 SSH keys, only the first 100 are ingested", userNode.Login)
+		}
+		for _, keyNode := range userNode.PublicKeys.Nodes {
+			fingerprint := sshKeyFingerprint(keyNode.Key)
+			if fingerprint == "" {
+				continue
+			}
+			usersKeys = append(usersKeys, map[string]string{
+				"fingerprint": fingerprint,
+				"url":         userNode.URL,
+			})
+		}
+	}
+
+	ing.db.Run(`
+	UNWIND $usersKeys AS userKey
+
+	MERGE (k:SSHKey{id: userKey.fingerprint})
+
+	SET k.fingerprint = userKey.fingerprint,
+	k.session = $session
+
+	WITH k, userKey
+
+	MATCH (u:User{id: userKey.url})
+	MERGE (u)-[rel:HAS_SSH_KEY]->(k)
+	SET rel.session = $session
+	`, map[string]interface{}{"usersKeys": usersKeys, "session": ing.session})
+}
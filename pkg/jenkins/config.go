@@ -0,0 +1,114 @@
+package jenkins
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/ovotech/gitoops/internal/utils"
+)
+
+// Matches credentials referenced in pipeline scripts, i.e. `credentials('id')` in environment
+// directives and `credentialsId: 'id'` in withCredentials bindings and steps such as git or sshagent.
+var credentialRefRegex = regexp.MustCompile(
+	`credentials\(\s*['"]([^'"]+)['"]\s*\)|credentialsId\s*:\s*['"]([^'"]+)['"]`,
+)
+
+// Matches the XML declaration of a document.
+var xmlDeclarationRegex = regexp.MustCompile(`^\s*<\?xml[^>]*\?>`)
+
+// What we care about in a job's config.xml.
+type jobConfig struct {
+	scmURLs       []string
+	credentialIDs []string
+	// the path of the Jenkinsfile of pipeline and multibranch jobs
+	scriptPath string
+}
+
+// Parses a job's config.xml for the repositories it builds, the credentials it references (directly
+// or in an inline pipeline script) and the path of its Jenkinsfile. Plugins store these in elements
+// of well-known names wherever they see fit, so we look for them anywhere in the document.
+func parseJobConfig(data []byte) jobConfig {
+	config := jobConfig{scmURLs: []string{}, credentialIDs: []string{}}
+	var githubAPIURI, githubOwner, githubRepository string
+
+	// Jenkins writes XML 1.1 declarations, which the decoder rejects
+	data = xmlDeclarationRegex.ReplaceAll(data, nil)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	stack := []string{}
+	text := ""
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			text = ""
+		case xml.CharData:
+			text += string(t)
+		case xml.EndElement:
+			parent := ""
+			if len(stack) > 1 {
+				parent = stack[len(stack)-2]
+			}
+			value := strings.TrimSpace(text)
+			switch {
+			case value == "":
+			case t.Name.Local == "credentialsId":
+				config.credentialIDs = append(config.credentialIDs, value)
+			case t.Name.Local == "url" && parent == "hudson.plugins.git.UserRemoteConfig",
+				t.Name.Local == "remote",
+				t.Name.Local == "repositoryUrl":
+				config.scmURLs = append(config.scmURLs, utils.RepositoryURL(value))
+			case t.Name.Local == "apiUri":
+				githubAPIURI = value
+			case t.Name.Local == "repoOwner":
+				githubOwner = value
+			case t.Name.Local == "repository":
+				githubRepository = value
+			case t.Name.Local == "scriptPath":
+				config.scriptPath = value
+			case t.Name.Local == "script":
+				config.credentialIDs = append(config.credentialIDs, CredentialRefs(value)...)
+			}
+			stack = stack[:len(stack)-1]
+			text = ""
+		}
+	}
+
+	// GitHub branch sources configured without a repository URL
+	if githubOwner != "" && githubRepository != "" {
+		config.scmURLs = append(config.scmURLs, githubRepositoryURL(githubAPIURI, githubOwner, githubRepository))
+	}
+
+	config.scmURLs = utils.RemoveDuplicateStrings(config.scmURLs)
+	config.credentialIDs = utils.RemoveDuplicateStrings(config.credentialIDs)
+	return config
+}
+
+// Returns the credential IDs referenced in a pipeline script, be it inline or a Jenkinsfile.
+func CredentialRefs(script string) []string {
+	ids := []string{}
+	for _, match := range credentialRefRegex.FindAllStringSubmatch(script, -1) {
+		if match[1] != "" {
+			ids = append(ids, match[1])
+		} else {
+			ids = append(ids, match[2])
+		}
+	}
+	return utils.RemoveDuplicateStrings(ids)
+}
+
+// Returns the web URL of a GitHub repository given the API URL of a GitHub branch source, which is
+// empty for GitHub.com.
+func githubRepositoryURL(apiURI, owner, repository string) string {
+	host := "github.com"
+	if u, err := url.Parse(apiURI); err == nil && u.Host != "" && u.Hostname() != "api.github.com" {
+		host = u.Hostname()
+	}
+	return "https://" + host + "/" + owner + "/" + repository
+}
@@ -0,0 +1,139 @@
+package jenkins
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseJobConfig(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config string
+		want   jobConfig
+	}{
+		{
+			name: "freestyle job",
+			config: `<?xml version='1.1' encoding='UTF-8'?>
+<project>
+  <scm class="hudson.plugins.git.GitSCM">
+    <userRemoteConfigs>
+      <hudson.plugins.git.UserRemoteConfig>
+        <url>git@github.com:fakenews/aws-infra.git</url>
+        <credentialsId>github-deploy-key</credentialsId>
+      </hudson.plugins.git.UserRemoteConfig>
+    </userRemoteConfigs>
+  </scm>
+  <buildWrappers>
+    <org.jenkinsci.plugins.credentialsbinding.impl.SecretBuildWrapper>
+      <bindings>
+        <org.jenkinsci.plugins.credentialsbinding.impl.StringBinding>
+          <credentialsId>aws-secret-key</credentialsId>
+          <variable>AWS_SECRET_ACCESS_KEY</variable>
+        </org.jenkinsci.plugins.credentialsbinding.impl.StringBinding>
+      </bindings>
+    </org.jenkinsci.plugins.credentialsbinding.impl.SecretBuildWrapper>
+  </buildWrappers>
+  <builders>
+    <hudson.tasks.Shell>
+      <url>https://example.com/not/a/remote</url>
+    </hudson.tasks.Shell>
+  </builders>
+</project>`,
+			want: jobConfig{
+				scmURLs:       []string{"https://github.com/fakenews/aws-infra"},
+				credentialIDs: []string{"github-deploy-key", "aws-secret-key"},
+			},
+		},
+		{
+			name: "pipeline job from SCM",
+			config: `<?xml version='1.1' encoding='UTF-8'?>
+<flow-definition plugin="workflow-job">
+  <definition class="org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition">
+    <scm class="hudson.plugins.git.GitSCM">
+      <userRemoteConfigs>
+        <hudson.plugins.git.UserRemoteConfig>
+          <url>https://github.com/fakenews/api.git</url>
+        </hudson.plugins.git.UserRemoteConfig>
+      </userRemoteConfigs>
+    </scm>
+    <scriptPath>ci/Jenkinsfile</scriptPath>
+  </definition>
+</flow-definition>`,
+			want: jobConfig{
+				scmURLs:       []string{"https://github.com/fakenews/api"},
+				credentialIDs: []string{},
+				scriptPath:    "ci/Jenkinsfile",
+			},
+		},
+		{
+			name: "inline pipeline",
+			config: `<flow-definition>
+  <definition class="org.jenkinsci.plugins.workflow.cps.CpsFlowDefinition">
+    <script>pipeline {
+  environment { TOKEN = credentials('npm-token') }
+  stages { stage('deploy') { steps {
+    withCredentials([string(credentialsId: "aws-secret-key", variable: 'KEY')]) { sh 'make' }
+    sshagent(credentials: ['deploy-key']) { sh 'git push' }
+  } } }
+}</script>
+  </definition>
+</flow-definition>`,
+			want: jobConfig{
+				scmURLs:       []string{},
+				credentialIDs: []string{"npm-token", "aws-secret-key"},
+			},
+		},
+		{
+			name: "multibranch GitHub branch source",
+			config: `<org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject>
+  <sources>
+    <data>
+      <jenkins.branch.BranchSource>
+        <source class="org.jenkinsci.plugins.github_branch_source.GitHubSCMSource">
+          <apiUri>https://github.example.com/api/v3</apiUri>
+          <credentialsId>github-app</credentialsId>
+          <repoOwner>fakenews</repoOwner>
+          <repository>web</repository>
+        </source>
+      </jenkins.branch.BranchSource>
+    </data>
+  </sources>
+  <factory class="org.jenkinsci.plugins.workflow.multibranch.WorkflowBranchProjectFactory">
+    <scriptPath>Jenkinsfile</scriptPath>
+  </factory>
+</org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject>`,
+			want: jobConfig{
+				scmURLs:       []string{"https://github.example.com/fakenews/web"},
+				credentialIDs: []string{"github-app"},
+				scriptPath:    "Jenkinsfile",
+			},
+		},
+		{
+			name:   "missing config",
+			config: "",
+			want:   jobConfig{scmURLs: []string{}, credentialIDs: []string{}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parseJobConfig([]byte(tc.config)); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseJobConfig() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCredentialRefs(t *testing.T) {
+	script := `
+environment {
+  AWS = credentials( "aws-secret-key" )
+}
+git url: 'git@github.com:fakenews/api.git', credentialsId: 'github-deploy-key'
+withCredentials([usernamePassword(credentialsId: 'aws-secret-key', usernameVariable: 'U')]) {}
+`
+	want := []string{"aws-secret-key", "github-deploy-key"}
+	if got := CredentialRefs(script); !reflect.DeepEqual(got, want) {
+		t.Errorf("CredentialRefs() = %v, want %v", got, want)
+	}
+}
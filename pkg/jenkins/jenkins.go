@@ -0,0 +1,46 @@
+package jenkins
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
+)
+
+type Jenkins struct {
+	restclient   *RESTClient
+	db           *database.Database
+	url          string
+	organization string
+	session      string
+}
+
+// Returns a Jenkins ingestor for the Jenkins instance at url, authenticating with a username and API
+// token.
+func GetJenkins(db *database.Database, url, organization, username, token, session string) *Jenkins {
+	return &Jenkins{
+		restclient: &RESTClient{
+			client:   &http.Client{},
+			username: username,
+			token:    token,
+		},
+		db:           db,
+		url:          strings.TrimSuffix(url, "/") + "/",
+		organization: organization,
+		session:      session,
+	}
+}
+
+func (j *Jenkins) Sync() {
+	log.Info("Running JobsIngestor")
+	ji := JobsIngestor{
+		restclient:   j.restclient,
+		db:           j.db,
+		data:         &JobsData{},
+		jenkinsURL:   j.url,
+		organization: j.organization,
+		session:      j.session,
+	}
+	ji.Sync()
+}
@@ -0,0 +1,300 @@
+package jenkins
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
+)
+
+// Crawls the job tree of a Jenkins instance, ingesting folders, jobs and the metadata of the
+// credentials stored globally and in folders. We never see credentials' secret values.
+type JobsIngestor struct {
+	restclient   *RESTClient
+	db           *database.Database
+	data         *JobsData
+	jenkinsURL   string
+	organization string
+	session      string
+}
+
+type JobsData struct {
+	Folders     []JenkinsItem
+	Jobs        []JenkinsItem
+	Credentials []JenkinsCredential
+}
+
+type JenkinsItem struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Class    string `json:"_class"`
+	FullName string
+	// the URL of the parent folder, empty for top level items
+	FolderURL string
+	config    jobConfig
+}
+
+type JenkinsCredential struct {
+	ID          string `json:"id"`
+	TypeName    string `json:"typeName"`
+	DisplayName string `json:"displayName"`
+	Description string `json:"description"`
+	// the full name and URL of the folder holding the credential, empty for global credentials
+	Folder    string
+	FolderURL string
+	storeURL  string
+}
+
+type itemChildren struct {
+	Jobs []JenkinsItem `json:"jobs"`
+}
+
+type credentialStore struct {
+	Credentials []JenkinsCredential `json:"credentials"`
+}
+
+func (ing *JobsIngestor) fetchData() {
+	ing.fetchCredentials(ing.jenkinsURL+"credentials/store/system/domain/_", "", "")
+	ing.crawl(ing.jenkinsURL, "", "")
+}
+
+// Fetches the items of a folder (or the root of the instance), descending into subfolders.
+// Multibranch projects and organization folders' projects are ingested as jobs, as they hold the
+// configuration of the jobs they generate.
+func (ing *JobsIngestor) crawl(folderURL, folder, parentURL string) {
+	children := itemChildren{}
+	json.Unmarshal(ing.restclient.fetch(folderURL, "jobs[name,url,_class]"), &children)
+
+	for _, item := range children.Jobs {
+		item.FullName = strings.TrimPrefix(folder+"/"+item.Name, "/")
+		item.FolderURL = parentURL
+
+		if isFolder(item.Class) {
+			log.Debugf("Crawling folder %s", item.FullName)
+			ing.data.Folders = append(ing.data.Folders, item)
+			ing.fetchCredentials(
+				strings.TrimSuffix(item.URL, "/")+"/credentials/store/folder/domain/_",
+				item.FullName,
+				item.URL,
+			)
+			ing.crawl(item.URL, item.FullName, item.URL)
+			continue
+		}
+
+		item.config = parseJobConfig(ing.restclient.fetchConfig(item.URL))
+		ing.data.Jobs = append(ing.data.Jobs, item)
+	}
+}
+
+// Fetches the credentials of the global domain of a credential store.
+func (ing *JobsIngestor) fetchCredentials(storeURL, folder, folderURL string) {
+	store := credentialStore{}
+	json.Unmarshal(
+		ing.restclient.fetch(storeURL, "credentials[id,typeName,displayName,description]"),
+		&store,
+	)
+	for _, credential := range store.Credentials {
+		credential.Folder = folder
+		credential.FolderURL = folderURL
+		credential.storeURL = storeURL
+		ing.data.Credentials = append(ing.data.Credentials, credential)
+	}
+}
+
+// Folders and organization folders (e.g. GitHub organizations) hold other items and credentials.
+func isFolder(class string) bool {
+	return strings.HasSuffix(class, ".Folder") || strings.HasSuffix(class, ".OrganizationFolder")
+}
+
+func (ing *JobsIngestor) Sync() {
+	ing.fetchData()
+	ing.insertFolders()
+	ing.insertJobs()
+	ing.insertItemsFolders()
+	ing.insertCredentials()
+	ing.insertJobsCredentials()
+}
+
+func (ing *JobsIngestor) insertFolders() {
+	folders := []map[string]interface{}{}
+
+	for _, folder := range ing.data.Folders {
+		folders = append(folders, map[string]interface{}{
+			"url":      folder.URL,
+			"name":     folder.Name,
+			"fullName": folder.FullName,
+			"class":    folder.Class,
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $folders AS folder
+
+	MERGE (f:JenkinsFolder{id: folder.url})
+
+	SET f.name = folder.name,
+	f.fullName = folder.fullName,
+	f.class = folder.class,
+	f.jenkinsURL = $jenkinsURL,
+	f.session = $session
+	`, map[string]interface{}{
+		"folders":    folders,
+		"jenkinsURL": ing.jenkinsURL,
+		"session":    ing.session,
+	})
+}
+
+// Jobs are linked to the organization's repositories they build, matched on their SCM URLs.
+func (ing *JobsIngestor) insertJobs() {
+	jobs := []map[string]interface{}{}
+
+	for _, job := range ing.data.Jobs {
+		jobs = append(jobs, map[string]interface{}{
+			"url":        job.URL,
+			"name":       job.Name,
+			"fullName":   job.FullName,
+			"class":      job.Class,
+			"scriptPath": job.config.scriptPath,
+			"scmURLs":    job.config.scmURLs,
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $jobs AS job
+
+	MERGE (j:JenkinsJob{id: job.url})
+
+	SET j.name = job.name,
+	j.fullName = job.fullName,
+	j.class = job.class,
+	j.scriptPath = job.scriptPath,
+	j.scmURLs = job.scmURLs,
+	j.jenkinsURL = $jenkinsURL,
+	j.session = $session
+
+	WITH j, job
+	UNWIND job.scmURLs AS scmURL
+
+	MATCH (r:Repository)-[:OWNED_BY]->(:Organization{login: $organization})
+	WHERE toLower(r.id) = toLower(scmURL)
+	MERGE (r)-[rel:HAS_CI]->(j)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"jobs":         jobs,
+		"jenkinsURL":   ing.jenkinsURL,
+		"organization": ing.organization,
+		"session":      ing.session,
+	})
+}
+
+func (ing *JobsIngestor) insertItemsFolders() {
+	items := []map[string]interface{}{}
+
+	all := append(append([]JenkinsItem{}, ing.data.Folders...), ing.data.Jobs...)
+	for _, item := range all {
+		if item.FolderURL == "" {
+			continue
+		}
+		items = append(items, map[string]interface{}{
+			"url":       item.URL,
+			"folderURL": item.FolderURL,
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $items AS item
+
+	MATCH (i{id: item.url})
+	WHERE i:JenkinsJob OR i:JenkinsFolder
+	MATCH (f:JenkinsFolder{id: item.folderURL})
+	MERGE (i)-[rel:IN_FOLDER]->(f)
+	SET rel.session = $session
+	`, map[string]interface{}{"items": items, "session": ing.session})
+}
+
+// Credentials are identified by their store and ID, as folders may hold credentials with the same
+// ID as global ones (which they then take precedence over).
+func (ing *JobsIngestor) insertCredentials() {
+	credentials := []map[string]interface{}{}
+
+	for _, credential := range ing.data.Credentials {
+		scope := "global"
+		if credential.Folder != "" {
+			scope = "folder"
+		}
+		credentials = append(credentials, map[string]interface{}{
+			"id":           fmt.Sprintf("%x", md5.Sum([]byte(credential.storeURL+credential.ID))),
+			"credentialId": credential.ID,
+			"typeName":     credential.TypeName,
+			"displayName":  credential.DisplayName,
+			"description":  credential.Description,
+			"scope":        scope,
+			"folder":       credential.Folder,
+			"folderURL":    credential.FolderURL,
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $credentials AS credential
+
+	MERGE (c:JenkinsCredential{id: credential.id})
+
+	SET c.credentialId = credential.credentialId,
+	c.typeName = credential.typeName,
+	c.displayName = credential.displayName,
+	c.description = credential.description,
+	c.scope = credential.scope,
+	c.folder = credential.folder,
+	c.jenkinsURL = $jenkinsURL,
+	c.session = $session
+
+	WITH c, credential
+
+	MATCH (f:JenkinsFolder{id: credential.folderURL})
+	MERGE (f)-[rel:HAS_CREDENTIAL]->(c)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"credentials": credentials,
+		"jenkinsURL":  ing.jenkinsURL,
+		"session":     ing.session,
+	})
+}
+
+// Links jobs to the credentials their config references. Jenkins resolves credential IDs from the
+// job's closest folder up to the global store, so we pick the credential of the deepest folder.
+func (ing *JobsIngestor) insertJobsCredentials() {
+	jobs := []map[string]interface{}{}
+
+	for _, job := range ing.data.Jobs {
+		jobs = append(jobs, map[string]interface{}{
+			"url":           job.URL,
+			"credentialIds": job.config.credentialIDs,
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $jobs AS job
+
+	MATCH (j:JenkinsJob{id: job.url})
+	OPTIONAL MATCH (j)-[rel:USES_CREDENTIAL{source: "config"}]->()
+	DELETE rel
+	`, map[string]interface{}{"jobs": jobs})
+
+	ing.db.Run(`
+	UNWIND $jobs AS job
+
+	MATCH (j:JenkinsJob{id: job.url})
+	UNWIND job.credentialIds AS credentialId
+
+	MATCH (c:JenkinsCredential{credentialId: credentialId, jenkinsURL: j.jenkinsURL})
+	WHERE c.folder = "" OR j.fullName STARTS WITH c.folder + "/"
+	WITH j, credentialId, c ORDER BY size(c.folder) DESC
+	WITH j, credentialId, collect(c)[0] AS c
+
+	MERGE (j)-[rel:USES_CREDENTIAL{source: "config"}]->(c)
+	SET rel.session = $session
+	`, map[string]interface{}{"jobs": jobs, "session": ing.session})
+}
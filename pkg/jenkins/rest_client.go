@@ -0,0 +1,76 @@
+package jenkins
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type RESTClient struct {
+	client   *http.Client
+	username string
+	token    string
+}
+
+// Retrieves a Jenkins resource. Item URLs are absolute, as returned by the Jenkins API.
+func (c *RESTClient) call(resourceURL string) (int, []byte) {
+	log.Debugf("Issuing REST query %s", resourceURL)
+
+	req, err := http.NewRequest(
+		"GET",
+		resourceURL,
+		nil,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	req.SetBasicAuth(c.username, c.token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		panic(err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		panic(err)
+	}
+
+	return resp.StatusCode, body
+}
+
+// Retrieves the JSON API of a Jenkins object, e.g. a folder or a credential store, limited to the
+// tree of attributes given. Returns an empty object on errors. Missing objects are expected, e.g.
+// the credential stores of instances without the credentials plugin, so they're only logged as
+// Debug.
+func (c *RESTClient) fetch(objectURL, tree string) []byte {
+	u := strings.TrimSuffix(objectURL, "/") + "/api/json?tree=" + url.QueryEscape(tree)
+	code, body := c.call(u)
+	if code != 200 {
+		c.logFetchError(objectURL, code)
+		return []byte("{}")
+	}
+	return body
+}
+
+// Retrieves the config.xml of a job. Returns nil on errors, e.g. if we can't configure the job.
+func (c *RESTClient) fetchConfig(jobURL string) []byte {
+	code, body := c.call(strings.TrimSuffix(jobURL, "/") + "/config.xml")
+	if code != 200 {
+		c.logFetchError(jobURL, code)
+		return nil
+	}
+	return body
+}
+
+func (c *RESTClient) logFetchError(objectURL string, code int) {
+	if code == 404 {
+		log.Debugf("%d error on %s", code, objectURL)
+		return
+	}
+	log.Warnf("%d error on %s", code, objectURL)
+}
@@ -0,0 +1,74 @@
+package travis
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ovotech/gitoops/pkg/database"
+)
+
+type ProjectEnvVarsIngestor struct {
+	restclient  *RESTClient
+	db          *database.Database
+	data        *ProjectEnvVarsData
+	projectSlug string
+	travisId    int64
+	session     string
+}
+
+type ProjectEnvVarsData struct {
+	EnvVars []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		// public variables are displayed in build logs
+		Public bool `json:"public"`
+		// variables may only be available to builds of a branch
+		Branch string `json:"branch"`
+	} `json:"env_vars"`
+}
+
+func (ing *ProjectEnvVarsIngestor) fetchData() {
+	query := fmt.Sprintf("repo/%d/env_vars", ing.travisId)
+	data := ing.restclient.fetch(query, "env_vars")
+	json.Unmarshal(data, &ing.data)
+}
+
+func (ing *ProjectEnvVarsIngestor) Sync() {
+	ing.fetchData()
+	ing.insertProjectEnvVars()
+}
+
+func (ing *ProjectEnvVarsIngestor) insertProjectEnvVars() {
+	envVars := []map[string]interface{}{}
+
+	for _, envVar := range ing.data.EnvVars {
+		id := fmt.Sprintf("%x", md5.Sum([]byte(ing.projectSlug+envVar.Branch+envVar.Name)))
+		envVars = append(envVars, map[string]interface{}{
+			"id":     id,
+			"name":   envVar.Name,
+			"public": envVar.Public,
+			"branch": envVar.Branch,
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $envVars AS envVar
+
+	MERGE (v:EnvironmentVariable{id: envVar.id})
+
+	SET v.name = envVar.name,
+	v.displayedInBuildLog = envVar.public,
+	v.session = $session
+
+	WITH v, envVar
+	MATCH (p:TravisProject{id: $projectSlug})
+	MERGE (p)-[rel:EXPOSES_ENVIRONMENT_VARIABLE]->(v)
+	SET rel.branch = envVar.branch,
+	rel.session = $session
+	`, map[string]interface{}{
+		"envVars":     envVars,
+		"projectSlug": ing.projectSlug,
+		"session":     ing.session,
+	})
+}
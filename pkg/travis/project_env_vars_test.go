@@ -0,0 +1,40 @@
+package travis
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestProjectEnvVarsFetchData(t *testing.T) {
+	server := fakeTravisAPI(t, map[string]map[string]string{
+		"/repo/1/env_vars": {"0": `{
+			"@type": "env_vars",
+			"env_vars": [
+				{"id": "a", "name": "AWS_SECRET_ACCESS_KEY", "public": false, "branch": null},
+				{"id": "b", "name": "DEPLOY_ENV", "public": true, "branch": "main"}
+			]
+		}`},
+	})
+	defer server.Close()
+
+	ing := ProjectEnvVarsIngestor{
+		restclient: &RESTClient{client: &http.Client{}, baseURL: server.URL, token: "fake"},
+		data:       &ProjectEnvVarsData{},
+		travisId:   1,
+	}
+	ing.fetchData()
+
+	got := []string{}
+	for _, envVar := range ing.data.EnvVars {
+		got = append(got, fmt.Sprintf("%s public=%v branch=%q", envVar.Name, envVar.Public, envVar.Branch))
+	}
+	want := []string{
+		`AWS_SECRET_ACCESS_KEY public=false branch=""`,
+		`DEPLOY_ENV public=true branch="main"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fetchData() env vars = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package travis
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ovotech/gitoops/pkg/database"
+)
+
+// Ingests a project's settings, most importantly whether it builds pull requests and shares its
+// secrets with builds of pull requests from forks.
+type ProjectSettingsIngestor struct {
+	restclient  *RESTClient
+	db          *database.Database
+	data        *ProjectSettingsData
+	projectSlug string
+	travisId    int64
+	session     string
+}
+
+type ProjectSettingsData struct {
+	Settings []struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+	} `json:"settings"`
+}
+
+func (ing *ProjectSettingsIngestor) fetchData() {
+	query := fmt.Sprintf("repo/%d/settings", ing.travisId)
+	data := ing.restclient.fetch(query, "settings")
+	json.Unmarshal(data, &ing.data)
+}
+
+func (ing *ProjectSettingsIngestor) Sync() {
+	ing.fetchData()
+	ing.insertProjectSettings()
+}
+
+// Returns the boolean settings by name. Other settings (e.g. the number of concurrent jobs) aren't
+// security relevant.
+func (data *ProjectSettingsData) booleans() map[string]bool {
+	settings := map[string]bool{}
+	for _, setting := range data.Settings {
+		if value, ok := setting.Value.(bool); ok {
+			settings[setting.Name] = value
+		}
+	}
+	return settings
+}
+
+// Returns true if anyone able to fork the repository can open a pull request running their code
+// with the project's secrets.
+func sharesSecretsWithForks(settings map[string]bool) bool {
+	return settings["build_pull_requests"] && settings["share_encrypted_env_with_forks"]
+}
+
+func (ing *ProjectSettingsIngestor) insertProjectSettings() {
+	settings := ing.data.booleans()
+
+	ing.db.Run(`
+	MATCH (p:TravisProject{id: $projectSlug})
+
+	SET p.buildPushes = $buildPushes,
+	p.buildPullRequests = $buildPullRequests,
+	p.shareEncryptedEnvWithForks = $shareEncryptedEnvWithForks,
+	p.shareSSHKeysWithForks = $shareSSHKeysWithForks,
+	p.allowConfigImports = $allowConfigImports
+
+	WITH p
+
+	OPTIONAL MATCH (:Anyone)-[rel:CAN_RUN_CODE_WITH_SECRETS]->(p)
+	DELETE rel
+	`, map[string]interface{}{
+		"projectSlug":                ing.projectSlug,
+		"buildPushes":                settings["build_pushes"],
+		"buildPullRequests":          settings["build_pull_requests"],
+		"shareEncryptedEnvWithForks": settings["share_encrypted_env_with_forks"],
+		"shareSSHKeysWithForks":      settings["share_ssh_keys_with_forks"],
+		"allowConfigImports":         settings["allow_config_imports"],
+	})
+
+	if !sharesSecretsWithForks(settings) {
+		return
+	}
+
+	ing.db.Run(`
+	MERGE (a:Anyone{id: "anyone"})
+
+	WITH a
+
+	MATCH (p:TravisProject{id: $projectSlug})
+	MERGE (a)-[rel:CAN_RUN_CODE_WITH_SECRETS]->(p)
+	SET rel.session = $session
+	`, map[string]interface{}{"projectSlug": ing.projectSlug, "session": ing.session})
+}
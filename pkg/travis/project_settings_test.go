@@ -0,0 +1,72 @@
+package travis
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestProjectSettings(t *testing.T) {
+	testCases := []struct {
+		name        string
+		settings    string
+		want        map[string]bool
+		sharesForks bool
+	}{
+		{
+			name: "pull requests from forks with secrets",
+			settings: `{"settings": [
+				{"name": "build_pull_requests", "value": true},
+				{"name": "share_encrypted_env_with_forks", "value": true},
+				{"name": "maximum_number_of_builds", "value": 0}
+			]}`,
+			want:        map[string]bool{"build_pull_requests": true, "share_encrypted_env_with_forks": true},
+			sharesForks: true,
+		},
+		{
+			name: "pull requests without secrets",
+			settings: `{"settings": [
+				{"name": "build_pull_requests", "value": true},
+				{"name": "share_encrypted_env_with_forks", "value": false}
+			]}`,
+			want: map[string]bool{"build_pull_requests": true, "share_encrypted_env_with_forks": false},
+		},
+		{
+			name: "secrets without pull requests",
+			settings: `{"settings": [
+				{"name": "build_pull_requests", "value": false},
+				{"name": "share_encrypted_env_with_forks", "value": true}
+			]}`,
+			want: map[string]bool{"build_pull_requests": false, "share_encrypted_env_with_forks": true},
+		},
+		{
+			name:     "no settings",
+			settings: `{"settings": []}`,
+			want:     map[string]bool{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := fakeTravisAPI(t, map[string]map[string]string{
+				"/repo/1/settings": {"0": tc.settings},
+			})
+			defer server.Close()
+
+			ing := ProjectSettingsIngestor{
+				restclient: &RESTClient{client: &http.Client{}, baseURL: server.URL, token: "fake"},
+				data:       &ProjectSettingsData{},
+				travisId:   1,
+			}
+			ing.fetchData()
+
+			settings := ing.data.booleans()
+			if !reflect.DeepEqual(settings, tc.want) {
+				t.Errorf("booleans() = %v, want %v", settings, tc.want)
+			}
+			if got := sharesSecretsWithForks(settings); got != tc.sharesForks {
+				t.Errorf("sharesSecretsWithForks() = %v, want %v", got, tc.sharesForks)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package travis
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/ovotech/gitoops/pkg/database"
+)
+
+// Ingests the organization's Travis CI repositories, which we call projects to avoid confusion with
+// GitHub repositories, and links them to their repository.
+type ProjectsIngestor struct {
+	restclient   *RESTClient
+	db           *database.Database
+	data         *ProjectsData
+	organization string
+	session      string
+}
+
+type ProjectsData struct {
+	Repositories []struct {
+		ID      int    `json:"id"`
+		Name    string `json:"name"`
+		Slug    string `json:"slug"`
+		Active  bool   `json:"active"`
+		Private bool   `json:"private"`
+	} `json:"repositories"`
+}
+
+func (ing *ProjectsIngestor) fetchData() {
+	query := fmt.Sprintf("owner/%s/repos", url.PathEscape(ing.organization))
+	data := ing.restclient.fetch(query, "repositories")
+	json.Unmarshal(data, &ing.data)
+}
+
+func (ing *ProjectsIngestor) Sync() {
+	ing.fetchData()
+	ing.insertProjects()
+}
+
+func (ing *ProjectsIngestor) insertProjects() {
+	projects := []map[string]interface{}{}
+
+	for _, repository := range ing.data.Repositories {
+		// inactive repositories aren't built
+		if !repository.Active {
+			continue
+		}
+		projects = append(projects, map[string]interface{}{
+			"slug":     repository.Slug,
+			"travisId": repository.ID,
+			"name":     repository.Name,
+			"private":  repository.Private,
+		})
+	}
+
+	ing.db.Run(`
+	UNWIND $projects AS project
+
+	MERGE (p:TravisProject{id: project.slug})
+
+	SET p.slug = project.slug,
+	p.travisId = project.travisId,
+	p.name = project.name,
+	p.private = project.private,
+	p.organization = $organization,
+	p.session = $session
+
+	WITH p, project
+
+	MATCH (r:Repository{name: project.name})-[:OWNED_BY]->(:Organization{login: $organization})
+	MERGE (r)-[rel:HAS_CI]->(p)
+	SET rel.session = $session
+	`, map[string]interface{}{
+		"projects":     projects,
+		"organization": ing.organization,
+		"session":      ing.session,
+	})
+}
@@ -0,0 +1,130 @@
+package travis
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	gabs "github.com/Jeffail/gabs/v2"
+	log "github.com/sirupsen/logrus"
+)
+
+// Travis API v3 pages are capped at 100 items.
+const pageLimit = 100
+
+type RESTClient struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+type RESTError struct {
+	code    int
+	message string
+	count   int
+}
+
+// Retrieves a single page for a REST query.
+func (c *RESTClient) call(resourcePath string, offset int) (int, []byte) {
+	log.Debugf("Issuing REST query %s offset %d", resourcePath, offset)
+
+	// resource paths are escaped, e.g. owners are given as url.PathEscape(owner)
+	u, _ := url.Parse(c.baseURL)
+	u.RawPath = path.Join(u.EscapedPath(), resourcePath)
+	u.Path, _ = url.PathUnescape(u.RawPath)
+	req, err := http.NewRequest(
+		"GET",
+		u.String(),
+		nil,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Add("Authorization", "token "+c.token)
+	req.Header.Add("Travis-API-Version", "3")
+
+	q := req.URL.Query()
+	q.Add("limit", fmt.Sprint(pageLimit))
+	q.Add("offset", fmt.Sprint(offset))
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		panic(err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		panic(err)
+	}
+
+	return resp.StatusCode, body
+}
+
+// Retrieves all pages for a REST URL path and returns the collection's items, e.g. the
+// "repositories" of an owner's repositories.
+func (c *RESTClient) fetch(resourcePath, collection string) []byte {
+	data := gabs.New()
+	data.Array(collection)
+	offset := 0
+
+	// map status code to RESTError
+	errorTracker := map[int]RESTError{}
+	for {
+		code, resp := c.call(resourcePath, offset)
+
+		parsedResp, err := gabs.ParseJSON(resp)
+		if err != nil {
+			panic(err)
+		}
+
+		if code != 200 {
+			c.trackFetchErrors(code, parsedResp, errorTracker)
+			break
+		}
+
+		for _, item := range parsedResp.Path(collection).Children() {
+			data.ArrayAppend(item.Data(), collection)
+		}
+
+		// unpaginated collections don't have pagination info
+		if isLast, ok := parsedResp.Path("@pagination.is_last").Data().(bool); !ok || isLast {
+			break
+		}
+		offset += pageLimit
+	}
+
+	c.logFetchErrors(resourcePath, errorTracker)
+
+	return data.Bytes()
+}
+
+func (c *RESTClient) trackFetchErrors(
+	code int,
+	parsedResp *gabs.Container,
+	errorTracker map[int]RESTError,
+) {
+	if _, ok := errorTracker[code]; ok {
+		var resterror = errorTracker[code]
+		resterror.count++
+		errorTracker[code] = resterror
+	} else {
+		message, _ := parsedResp.Path("error_message").Data().(string)
+		errorTracker[code] = RESTError{
+			code:    code,
+			message: message,
+			count:   1,
+		}
+	}
+}
+
+// Logs REST errors to output. These are logged as Warn because we don't really expect any REST
+// errors.
+func (c *RESTClient) logFetchErrors(resourcePath string, errorTracker map[int]RESTError) {
+	for _, e := range errorTracker {
+		log.Warnf("%d errors on %s: %s", e.count, resourcePath, e.message)
+	}
+}
@@ -0,0 +1,73 @@
+package travis
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// Serves canned Travis CI API responses by escaped path and offset, checking the client's headers.
+func fakeTravisAPI(t *testing.T, responses map[string]map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token fake" || r.Header.Get("Travis-API-Version") != "3" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error_message": "access denied"}`)
+			return
+		}
+		if limit := r.URL.Query().Get("limit"); limit != fmt.Sprint(pageLimit) {
+			t.Errorf("limit = %s, want %d", limit, pageLimit)
+		}
+		response, ok := responses[r.URL.EscapedPath()][r.URL.Query().Get("offset")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error_message": "resource not found"}`)
+			return
+		}
+		fmt.Fprint(w, response)
+	}))
+}
+
+func TestRESTClientFetch(t *testing.T) {
+	server := fakeTravisAPI(t, map[string]map[string]string{
+		"/owner/fake%20news/repos": {
+			"0": `{
+				"@pagination": {"is_last": false},
+				"repositories": [{"id": 1, "slug": "fake news/api"}, {"id": 2, "slug": "fake news/web"}]
+			}`,
+			"100": `{
+				"@pagination": {"is_last": true},
+				"repositories": [{"id": 3, "slug": "fake news/aws-infra"}]
+			}`,
+		},
+		"/repo/1/settings": {
+			"0": `{"settings": [{"name": "build_pushes", "value": true}]}`,
+		},
+	})
+	defer server.Close()
+
+	c := &RESTClient{client: &http.Client{}, baseURL: server.URL, token: "fake"}
+
+	projects := ProjectsData{}
+	json.Unmarshal(c.fetch("owner/fake%20news/repos", "repositories"), &projects)
+	got := []string{}
+	for _, repository := range projects.Repositories {
+		got = append(got, repository.Slug)
+	}
+	if want := []string{"fake news/api", "fake news/web", "fake news/aws-infra"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fetch() of a paginated collection = %v, want %v", got, want)
+	}
+
+	// unpaginated collections don't have pagination info
+	settings := ProjectSettingsData{}
+	json.Unmarshal(c.fetch("repo/1/settings", "settings"), &settings)
+	if len(settings.Settings) != 1 {
+		t.Errorf("fetch() of an unpaginated collection = %+v, want one setting", settings)
+	}
+
+	if got := string(c.fetch("repo/2/settings", "settings")); got != `{"settings":[]}` {
+		t.Errorf("fetch() of a missing collection = %s, want an empty collection", got)
+	}
+}
@@ -0,0 +1,75 @@
+package travis
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ovotech/gitoops/pkg/database"
+	log "github.com/sirupsen/logrus"
+)
+
+type Travis struct {
+	restclient   *RESTClient
+	db           *database.Database
+	organization string
+	session      string
+}
+
+// Returns a Travis CI ingestor for the Travis CI API at url, authenticating with an API token.
+func GetTravis(db *database.Database, url, organization, token, session string) *Travis {
+	return &Travis{
+		restclient: &RESTClient{
+			client:  &http.Client{},
+			baseURL: strings.TrimSuffix(url, "/"),
+			token:   token,
+		},
+		db:           db,
+		organization: organization,
+		session:      session,
+	}
+}
+
+func (t *Travis) Sync() {
+	log.Info("Running ProjectsIngestor")
+	pi := ProjectsIngestor{
+		restclient:   t.restclient,
+		db:           t.db,
+		data:         &ProjectsData{},
+		organization: t.organization,
+		session:      t.session,
+	}
+	pi.Sync()
+
+	// queries existing TravisProjects
+	projectRecords := t.db.Run(
+		`MATCH (p:TravisProject{session:$session, organization:$organization})
+		RETURN p.id as projectSlug, p.travisId as travisId`,
+		map[string]interface{}{"session": t.session, "organization": t.organization},
+	)
+	for projectRecords.Next() {
+		projectSlug, _ := projectRecords.Record().Get("projectSlug")
+		travisId, _ := projectRecords.Record().Get("travisId")
+
+		log.Infof("Running ProjectEnvVarsIngestor on project %s", projectSlug)
+		pevi := ProjectEnvVarsIngestor{
+			restclient:  t.restclient,
+			db:          t.db,
+			data:        &ProjectEnvVarsData{},
+			projectSlug: projectSlug.(string),
+			travisId:    travisId.(int64),
+			session:     t.session,
+		}
+		pevi.Sync()
+
+		log.Infof("Running ProjectSettingsIngestor on project %s", projectSlug)
+		psi := ProjectSettingsIngestor{
+			restclient:  t.restclient,
+			db:          t.db,
+			data:        &ProjectSettingsData{},
+			projectSlug: projectSlug.(string),
+			travisId:    travisId.(int64),
+			session:     t.session,
+		}
+		psi.Sync()
+	}
+}
@@ -42,6 +42,9 @@ _This file is generated by `./scripts/generate_schema_doc.py`_
 | {{data["relationships"]["outbound"][loop.index-1]}} | {{data["relationships"]["inbound"][loop.index-1]}} |
 {%- endfor %}
 {% endfor %}
+## Notes
+
+- `TRIGGERED_PIPELINE` relationships from users to CircleCI projects have a `count` of the pipelines the user triggered among the project's latest pipelines (the first page the API returns), not of all the project's pipelines, and the `lastAt` time of the latest one.
 """
 
 
@@ -0,0 +1,150 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ovotech/gitoops/pkg/buildkite"
+)
+
+const buildkiteOrganization = "fakenews-buildkite"
+
+// Serves canned Buildkite GraphQL and REST API responses for an aws-infra pipeline building pull
+// requests from forks on a cluster's deploy queue, which a platform team can build.
+func fakeBuildkiteAPI() *httptest.Server {
+	// GraphQL queries are told apart by the connection they read
+	graphQLResponses := map[string]string{
+		"teams(": `{"data": {"organization": {"teams": {
+			"pageInfo": {"endCursor": null, "hasNextPage": false},
+			"edges": [{"node": {
+				"uuid": "team-1", "name": "Platform", "slug": "platform", "privacy": "VISIBLE",
+				"pipelines": {
+					"pageInfo": {"hasNextPage": false},
+					"edges": [{"node": {"accessLevel": "BUILD_AND_READ", "pipeline": {"uuid": "pipeline-1"}}}]
+				}
+			}}]
+		}}}}`,
+		"clusters(": `{"data": {"organization": {"clusters": {
+			"pageInfo": {"endCursor": null, "hasNextPage": false},
+			"edges": [{"node": {
+				"uuid": "cluster-1", "name": "Production", "description": "",
+				"queues": {
+					"pageInfo": {"hasNextPage": false},
+					"edges": [{"node": {"uuid": "queue-1", "key": "deploy", "description": "Deployers"}}]
+				}
+			}}]
+		}}}}`,
+		"pipelines(": fmt.Sprintf(`{"data": {"organization": {"pipelines": {
+			"pageInfo": {"endCursor": null, "hasNextPage": false},
+			"edges": [{"node": {
+				"uuid": "pipeline-1", "name": "aws-infra", "slug": "aws-infra",
+				"url": "https://buildkite.com/%s/aws-infra", "visibility": "PRIVATE", "defaultBranch": "main",
+				"repository": {"url": "git@github.com:%s/aws-infra.git"},
+				"cluster": {"uuid": "cluster-1"}
+			}}]
+		}}}}`, buildkiteOrganization, organization),
+		"organization(": fmt.Sprintf(`{"data": {"organization": {
+			"uuid": "organization-1", "name": "Fake News", "slug": "%s"
+		}}}`, buildkiteOrganization),
+	}
+	restResponses := map[string]string{
+		fmt.Sprintf("/v2/organizations/%s/pipelines", buildkiteOrganization): `[{
+			"slug": "aws-infra",
+			"provider": {"id": "github", "settings": {
+				"trigger_mode": "code",
+				"build_pull_requests": true,
+				"build_pull_request_forks": true,
+				"prefix_pull_request_fork_branch_names": true
+			}}
+		}]`,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fake" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message": "Authentication required"}`)
+			return
+		}
+
+		if r.URL.Path == "/graphql" {
+			body := struct {
+				Query string `json:"query"`
+			}{}
+			json.NewDecoder(r.Body).Decode(&body)
+			for _, connection := range []string{"teams(", "clusters(", "pipelines(", "organization("} {
+				if strings.Contains(body.Query, connection) {
+					fmt.Fprint(w, graphQLResponses[connection])
+					return
+				}
+			}
+		}
+
+		response, ok := restResponses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+			return
+		}
+		if r.URL.Query().Get("page") != "1" {
+			response = "[]"
+		}
+		fmt.Fprint(w, response)
+	}))
+}
+
+func TestBuildkiteRelationships(t *testing.T) {
+	server := fakeBuildkiteAPI()
+	defer server.Close()
+
+	bk := buildkite.GetBuildkite(
+		db,
+		server.URL+"/graphql",
+		server.URL+"/v2",
+		organization,
+		buildkiteOrganization,
+		"fake",
+		session,
+	)
+	bk.Sync()
+
+	var testCases = []testCase{
+		{
+			a: node{label: "Repository", property: property{name: "name", value: "aws-infra"}},
+			r: relationship{label: "HAS_CI"},
+			b: node{label: "BuildkitePipeline", property: property{name: "id", value: "pipeline-1"}},
+		},
+		{
+			a: node{label: "BuildkitePipeline", property: property{name: "id", value: "pipeline-1"}},
+			r: relationship{label: "OWNED_BY"},
+			b: node{label: "BuildkiteOrganization", property: property{name: "id", value: buildkiteOrganization}},
+		},
+		{
+			a: node{label: "BuildkitePipeline", property: property{name: "id", value: "pipeline-1"}},
+			r: relationship{label: "USES_CLUSTER"},
+			b: node{label: "BuildkiteCluster", property: property{name: "id", value: "cluster-1"}},
+		},
+		{
+			a: node{label: "BuildkiteCluster", property: property{name: "id", value: "cluster-1"}},
+			r: relationship{label: "HAS_QUEUE"},
+			b: node{label: "BuildkiteQueue", property: property{name: "key", value: "deploy"}},
+		},
+		{
+			a: node{label: "BuildkiteTeam", property: property{name: "id", value: "team-1"}},
+			r: relationship{label: "HAS_PERMISSION_ON"},
+			b: node{label: "BuildkitePipeline", property: property{name: "id", value: "pipeline-1"}},
+		},
+		{
+			a: node{label: "Anyone", property: property{name: "id", value: "anyone"}},
+			r: relationship{label: "CAN_RUN_CODE_WITH_SECRETS"},
+			b: node{label: "BuildkitePipeline", property: property{name: "id", value: "pipeline-1"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		runTestCase(tc, t)
+	}
+}
@@ -0,0 +1,31 @@
+package e2e
+
+import "testing"
+
+func TestWorkflowRelationships(t *testing.T) {
+	var testCases = []testCase{
+		{
+			a: node{
+				label: "Workflow",
+				property: property{
+					name:  "name",
+					value: "GitHub Actions Demo",
+				},
+			},
+			r: relationship{
+				label: "HAS_JOB",
+			},
+			b: node{
+				label: "Job",
+				property: property{
+					name:  "key",
+					value: "Explore-GitHub-Actions",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		runTestCase(tc, t)
+	}
+}
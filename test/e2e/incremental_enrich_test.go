@@ -0,0 +1,149 @@
+package e2e
+
+import (
+	"crypto/md5"
+	"fmt"
+	"testing"
+
+	"github.com/ovotech/gitoops/pkg/enrich"
+)
+
+const incrementalOrganization = "gitoops-e2e-incremental"
+
+const incrementalWorkflow = `
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make
+  lint:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make lint
+`
+
+// Creates or updates a synthetic organization with a single repository and workflow file, as the
+// GitHub ingestors would in the given session.
+func ingestIncrementalWorkflow(session, text string) {
+	db.Run(`
+	MERGE (o:Organization{login: $organization})
+	SET o.session = $session
+
+	MERGE (r:Repository{id: $repoURL})
+	SET r.url = $repoURL,
+	r.name = "incremental",
+	r.defaultBranch = "main",
+	r.session = $session
+	MERGE (r)-[:OWNED_BY]->(o)
+
+	MERGE (f:File{id: $repoURL + "/ci.yml"})
+	SET f.path = ".github/workflows/ci.yml",
+	f.text = $text,
+	f.textHash = $textHash,
+	f.ref = "main",
+	f.session = $session
+	MERGE (r)-[:HAS_CI_CONFIGURATION_FILE]->(f)
+	`, map[string]interface{}{
+		"organization": incrementalOrganization,
+		"repoURL":      "https://github.com/" + incrementalOrganization + "/incremental",
+		"text":         text,
+		"textHash":     fmt.Sprintf("%x", md5.Sum([]byte(text))),
+		"session":      session,
+	})
+}
+
+// Returns the sessions of the jobs of the synthetic workflow, by key.
+func incrementalJobSessions(t *testing.T) map[string]string {
+	records := db.Run(`
+	MATCH (:Organization{login: $organization})<-[:OWNED_BY]-(:Repository)
+	-[:HAS_CI_CONFIGURATION_FILE]->(:File)-[:DEFINES_WORKFLOW]->(:Workflow)-[rel:HAS_JOB]->(j:Job)
+	RETURN j.key as key, j.session as session, rel.session as relSession
+	`, map[string]interface{}{"organization": incrementalOrganization})
+
+	sessions := map[string]string{}
+	for records.Next() {
+		key, _ := records.Record().Get("key")
+		session, _ := records.Record().Get("session")
+		relSession, _ := records.Record().Get("relSession")
+		if session != relSession {
+			t.Errorf("job %v has session %v but its HAS_JOB relationship has %v", key, session, relSession)
+		}
+		sessions[key.(string)], _ = session.(string)
+	}
+	return sessions
+}
+
+func TestIncrementalEnrichment(t *testing.T) {
+	defer db.Run(`
+	MATCH (o:Organization{login: $organization})<-[:OWNED_BY]-(r:Repository)
+	OPTIONAL MATCH (r)-[:HAS_CI_CONFIGURATION_FILE]->(f:File)
+	OPTIONAL MATCH (w:Workflow)-[:HAS_JOB]->(j:Job)
+	WHERE w.enrichedFrom = r.id
+	OPTIONAL MATCH (j)-[:HAS_STEP]->(s:Step)
+	DETACH DELETE o, r, f, w, j, s
+	`, map[string]interface{}{"organization": incrementalOrganization})
+
+	enrichSession := func(session string) {
+		enrich.GetEnricher(db, incrementalOrganization, session, enrich.DefaultRules(), false).Enrich()
+	}
+
+	ingestIncrementalWorkflow("incremental-1", incrementalWorkflow)
+	enrichSession("incremental-1")
+	want := map[string]string{"build": "incremental-1", "lint": "incremental-1"}
+	if got := incrementalJobSessions(t); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("after the first enrichment, jobs = %v, want %v", got, want)
+	}
+
+	// unchanged files aren't enriched again, but what we derived from them gets the new session
+	ingestIncrementalWorkflow("incremental-2", incrementalWorkflow)
+	enrichSession("incremental-2")
+	want = map[string]string{"build": "incremental-2", "lint": "incremental-2"}
+	if got := incrementalJobSessions(t); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("after enriching unchanged files, jobs = %v, want %v", got, want)
+	}
+
+	// a job removed from a changed file is removed from the graph
+	ingestIncrementalWorkflow("incremental-3", `
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make
+`)
+	enrichSession("incremental-3")
+	want = map[string]string{"build": "incremental-3"}
+	if got := incrementalJobSessions(t); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("after enriching a changed file, jobs = %v, want %v", got, want)
+	}
+
+	// a stale file, e.g. deleted from the repository, doesn't get the repository enriched again
+	db.Run(`
+	MATCH (r:Repository{id: $repoURL})
+	MERGE (f:File{id: $repoURL + "/deleted.yml"})
+	SET f.path = ".github/workflows/deleted.yml", f.text = "on: push", f.ref = "main",
+	f.session = "incremental-1"
+	MERGE (r)-[:HAS_CI_CONFIGURATION_FILE]->(f)
+	`, map[string]interface{}{"repoURL": "https://github.com/" + incrementalOrganization + "/incremental"})
+	enrichedAt := incrementalEnrichedAt()
+	enrichSession("incremental-4")
+	if got := incrementalEnrichedAt(); got != enrichedAt {
+		t.Errorf("after enriching with a stale file, enrichedAt = %v, want %v", got, enrichedAt)
+	}
+}
+
+// Returns when the synthetic workflow file was last enriched.
+func incrementalEnrichedAt() interface{} {
+	records := db.Run(`
+	MATCH (f:File{id: $id})
+	RETURN f.enrichedAt as enrichedAt
+	`, map[string]interface{}{
+		"id": "https://github.com/" + incrementalOrganization + "/incremental/ci.yml",
+	})
+	if !records.Next() {
+		return nil
+	}
+	enrichedAt, _ := records.Record().Get("enrichedAt")
+	return enrichedAt
+}
@@ -0,0 +1,110 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ovotech/gitoops/pkg/jenkins"
+)
+
+// Serves canned Jenkins API responses for a platform folder holding an aws-infra pipeline job, which
+// builds the aws-infra repository with a credential of the folder. Item URLs are absolute in the
+// Jenkins API, so {base} is replaced with the server's URL.
+func fakeJenkinsAPI() *httptest.Server {
+	responses := map[string]string{
+		"/api/json": `{
+			"jobs": [
+				{"_class": "com.cloudbees.hudson.plugins.folder.Folder", "name": "platform", "url": "{base}/job/platform/"}
+			]
+		}`,
+		"/credentials/store/system/domain/_/api/json": `{
+			"credentials": [
+				{"id": "github-deploy-key", "typeName": "SSH Username with private key", "displayName": "git", "description": ""}
+			]
+		}`,
+		"/job/platform/api/json": `{
+			"jobs": [
+				{"_class": "org.jenkinsci.plugins.workflow.job.WorkflowJob", "name": "aws-infra", "url": "{base}/job/platform/job/aws-infra/"}
+			]
+		}`,
+		"/job/platform/credentials/store/folder/domain/_/api/json": `{
+			"credentials": [
+				{"id": "aws-secret-key", "typeName": "Secret text", "displayName": "aws-secret-key", "description": "AWS"}
+			]
+		}`,
+		"/job/platform/job/aws-infra/config.xml": fmt.Sprintf(`<?xml version='1.1' encoding='UTF-8'?>
+<flow-definition plugin="workflow-job">
+  <definition class="org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition">
+    <scm class="hudson.plugins.git.GitSCM">
+      <userRemoteConfigs>
+        <hudson.plugins.git.UserRemoteConfig>
+          <url>git@github.com:%s/aws-infra.git</url>
+          <credentialsId>github-deploy-key</credentialsId>
+        </hudson.plugins.git.UserRemoteConfig>
+      </userRemoteConfigs>
+    </scm>
+    <scriptPath>Jenkinsfile</scriptPath>
+  </definition>
+  <properties>
+    <org.jenkinsci.plugins.credentialsbinding.impl.SecretBuildWrapper>
+      <credentialsId>aws-secret-key</credentialsId>
+    </org.jenkinsci.plugins.credentialsbinding.impl.SecretBuildWrapper>
+  </properties>
+</flow-definition>`, organization),
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		username, token, _ := r.BasicAuth()
+		if !ok || username != "user" || token != "fake" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, strings.ReplaceAll(response, "{base}", "http://"+r.Host))
+	}))
+}
+
+func TestJenkinsRelationships(t *testing.T) {
+	server := fakeJenkinsAPI()
+	defer server.Close()
+
+	j := jenkins.GetJenkins(db, server.URL, organization, "user", "fake", session)
+	j.Sync()
+
+	jobURL := server.URL + "/job/platform/job/aws-infra/"
+	folderURL := server.URL + "/job/platform/"
+	var testCases = []testCase{
+		{
+			a: node{label: "Repository", property: property{name: "name", value: "aws-infra"}},
+			r: relationship{label: "HAS_CI"},
+			b: node{label: "JenkinsJob", property: property{name: "id", value: jobURL}},
+		},
+		{
+			a: node{label: "JenkinsJob", property: property{name: "id", value: jobURL}},
+			r: relationship{label: "IN_FOLDER"},
+			b: node{label: "JenkinsFolder", property: property{name: "id", value: folderURL}},
+		},
+		{
+			a: node{label: "JenkinsFolder", property: property{name: "id", value: folderURL}},
+			r: relationship{label: "HAS_CREDENTIAL"},
+			b: node{label: "JenkinsCredential", property: property{name: "credentialId", value: "aws-secret-key"}},
+		},
+		{
+			a: node{label: "JenkinsJob", property: property{name: "id", value: jobURL}},
+			r: relationship{label: "USES_CREDENTIAL"},
+			b: node{label: "JenkinsCredential", property: property{name: "credentialId", value: "aws-secret-key"}},
+		},
+		{
+			a: node{label: "JenkinsJob", property: property{name: "id", value: jobURL}},
+			r: relationship{label: "USES_CREDENTIAL"},
+			b: node{label: "JenkinsCredential", property: property{name: "credentialId", value: "github-deploy-key"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		runTestCase(tc, t)
+	}
+}
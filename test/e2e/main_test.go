@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/ovotech/gitoops/pkg/database"
+	"github.com/ovotech/gitoops/pkg/enrich"
 	"github.com/ovotech/gitoops/pkg/github"
 )
 
@@ -87,8 +88,18 @@ func runTestCase(tc testCase, t *testing.T) {
 }
 
 func TestMain(m *testing.M) {
-	gh := github.GetGitHub(db, githubRESTURL, githubGraphQLURL, githubToken, organization, session)
+	gh := github.GetGitHub(
+		db,
+		githubRESTURL,
+		githubGraphQLURL,
+		githubToken,
+		organization,
+		session,
+		github.DefaultFilePatterns,
+	)
 	gh.SyncByIngestorNames(ingestors)
+	en := enrich.GetEnricher(db, organization, session, enrich.DefaultRules(), true)
+	en.Enrich()
 	exitVal := m.Run()
 	os.Exit(exitVal)
 }
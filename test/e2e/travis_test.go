@@ -0,0 +1,76 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ovotech/gitoops/pkg/travis"
+)
+
+// Serves canned Travis CI API responses for an aws-infra repository building pull requests from
+// forks with its secrets.
+func fakeTravisAPI() *httptest.Server {
+	responses := map[string]string{
+		fmt.Sprintf("/owner/%s/repos", organization): fmt.Sprintf(`{
+			"@pagination": {"is_last": true},
+			"repositories": [
+				{"id": 1, "name": "aws-infra", "slug": "%s/aws-infra", "active": true, "private": false}
+			]
+		}`, organization),
+		"/repo/1/env_vars": `{
+			"env_vars": [
+				{"id": "a", "name": "AWS_SECRET_ACCESS_KEY", "public": false, "branch": null},
+				{"id": "b", "name": "DEPLOY_ENV", "public": true, "branch": "main"}
+			]
+		}`,
+		"/repo/1/settings": `{
+			"settings": [
+				{"name": "build_pull_requests", "value": true},
+				{"name": "share_encrypted_env_with_forks", "value": true}
+			]
+		}`,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		if !ok || r.Header.Get("Authorization") != "token fake" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error_message": "not found"}`)
+			return
+		}
+		fmt.Fprint(w, response)
+	}))
+}
+
+func TestTravisRelationships(t *testing.T) {
+	server := fakeTravisAPI()
+	defer server.Close()
+
+	tr := travis.GetTravis(db, server.URL, organization, "fake", session)
+	tr.Sync()
+
+	slug := organization + "/aws-infra"
+	var testCases = []testCase{
+		{
+			a: node{label: "Repository", property: property{name: "name", value: "aws-infra"}},
+			r: relationship{label: "HAS_CI"},
+			b: node{label: "TravisProject", property: property{name: "slug", value: slug}},
+		},
+		{
+			a: node{label: "TravisProject", property: property{name: "slug", value: slug}},
+			r: relationship{label: "EXPOSES_ENVIRONMENT_VARIABLE"},
+			b: node{label: "EnvironmentVariable", property: property{name: "name", value: "AWS_SECRET_ACCESS_KEY"}},
+		},
+		{
+			a: node{label: "Anyone", property: property{name: "id", value: "anyone"}},
+			r: relationship{label: "CAN_RUN_CODE_WITH_SECRETS"},
+			b: node{label: "TravisProject", property: property{name: "slug", value: slug}},
+		},
+	}
+
+	for _, tc := range testCases {
+		runTestCase(tc, t)
+	}
+}